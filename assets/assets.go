@@ -17,10 +17,34 @@
 
 package assets // import "go.elastic.co/go-licence-detector/assets"
 
-import _ "embed"
+import "embed"
 
 //go:embed licenses.db
 var LicenceDB []byte
 
+// Licences holds the texts of licences that are not part of the licence classifier's database (e.g. source-available
+// licences). They are added to LicenceDB when the embedded database is loaded.
+//
+//go:embed licences/*.txt
+var Licences embed.FS
+
+// Texts holds the canonical texts of SPDX licences, named <licence ID>.txt. They are rendered for dependencies whose
+// licence type is given by an override without a licence file.
+//
+//go:embed texts/*.txt
+var Texts embed.FS
+
+//go:embed categories.json
+var Categories []byte
+
 //go:embed rules.json
 var Rules []byte
+
+//go:embed compatibility.json
+var Compatibility []byte
+
+// SPDX holds the SPDX licence list, including deprecated licence IDs and their replacements, and the canonical IDs of
+// licence names used by the licence classifier that are not on the list.
+//
+//go:embed spdx.json
+var SPDX []byte
@@ -0,0 +1,133 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"go.elastic.co/go-licence-detector/cache"
+	"go.elastic.co/go-licence-detector/detector"
+)
+
+const cacheUsage = `Usage: go-licence-detector cache <command> [FLAGS]
+
+Commands:
+  stats    Show the number and size of the cached detection results.
+  prune    Remove the results cached for other licence databases or unused for a while.
+`
+
+// runCache handles the cache subcommand.
+func runCache(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, cacheUsage)
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "stats":
+		runCacheStats(args[1:])
+	case "prune":
+		runCachePrune(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown cache command: %s\n\n%s", args[0], cacheUsage)
+		os.Exit(2)
+	}
+}
+
+func runCacheStats(args []string) {
+	fs := flag.NewFlagSet("cache stats", flag.ExitOnError)
+	cacheDirFlag := fs.String("cacheDir", "", "Path to the cache directory. Uses go-licence-detector in the user cache directory if empty.")
+	licenceDataFlag := fs.String("licenceData", "", "Path to the licence database whose entries are marked as current. Uses embedded database if empty.")
+	_ = fs.Parse(args)
+
+	c, err := cache.Open(*cacheDirFlag)
+	if err != nil {
+		log.Fatalf("Failed to open cache: %v", err)
+	}
+
+	licenceDB, err := detector.LoadLicenceDB(*licenceDataFlag)
+	if err != nil {
+		log.Fatalf("Failed to load licence database: %v", err)
+	}
+
+	dbHash, err := detector.ClassifierHash(licenceDB)
+	if err != nil {
+		log.Fatalf("Failed to hash licence database: %v", err)
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		log.Fatalf("Failed to read cache: %v", err)
+	}
+
+	fmt.Printf("Directory: %s\n", c.Dir())
+	fmt.Printf("Entries:   %d\n", stats.Entries)
+	fmt.Printf("Size:      %d bytes\n", stats.Size)
+	if stats.Entries == 0 {
+		return
+	}
+
+	fmt.Printf("Last used: %s to %s\n", stats.Oldest.Format(time.RFC3339), stats.Newest.Format(time.RFC3339))
+	fmt.Println("Licence databases:")
+	for _, h := range slices.Sorted(maps.Keys(stats.ByDBHash)) {
+		current := ""
+		if h == dbHash {
+			current = " (current)"
+		}
+		fmt.Printf("  %s: %d entries%s\n", h, stats.ByDBHash[h], current)
+	}
+}
+
+func runCachePrune(args []string) {
+	fs := flag.NewFlagSet("cache prune", flag.ExitOnError)
+	cacheDirFlag := fs.String("cacheDir", "", "Path to the cache directory. Uses go-licence-detector in the user cache directory if empty.")
+	licenceDataFlag := fs.String("licenceData", "", "Path to the licence database whose entries are kept. Uses embedded database if empty.")
+	olderThanFlag := fs.Duration("olderThan", 30*24*time.Hour, "Remove the entries unused for longer than this duration. Entries are kept regardless of their age if zero.")
+	allFlag := fs.Bool("all", false, "Remove all entries.")
+	_ = fs.Parse(args)
+
+	c, err := cache.Open(*cacheDirFlag)
+	if err != nil {
+		log.Fatalf("Failed to open cache: %v", err)
+	}
+
+	licenceDB, err := detector.LoadLicenceDB(*licenceDataFlag)
+	if err != nil {
+		log.Fatalf("Failed to load licence database: %v", err)
+	}
+
+	dbHash, err := detector.ClassifierHash(licenceDB)
+	if err != nil {
+		log.Fatalf("Failed to hash licence database: %v", err)
+	}
+
+	removed, err := c.Prune(func(e cache.Entry, lastUsed time.Time) bool {
+		return *allFlag || e.DBHash != dbHash || (*olderThanFlag > 0 && time.Since(lastUsed) > *olderThanFlag)
+	})
+	if err != nil {
+		log.Fatalf("Failed to prune cache: %v", err)
+	}
+
+	fmt.Printf("Removed %d entries from %s\n", removed, c.Dir())
+}
@@ -0,0 +1,249 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package cache stores the licence files and licence types detected for module versions on disk, so that the
+// immutable modules of the module cache are not searched and classified again by later runs.
+package cache // import "go.elastic.co/go-licence-detector/cache"
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tempFileAge is the age after which temporary files left behind by interrupted runs are removed by Prune.
+const tempFileAge = time.Hour
+
+// Entry is the detection result of a module version.
+type Entry struct {
+	Module        string  `json:"module"`
+	Version       string  `json:"version"`
+	DBHash        string  `json:"dbHash"`                  // SHA-256 hash of the licence database of the classifier
+	SearchDepth   int     `json:"searchDepth"`             // maximum depth of the search for the licence file
+	LicenceFile   string  `json:"licenceFile,omitempty"`   // path of the licence file relative to the module directory, empty if none was found
+	LicenceSha256 string  `json:"licenceSha256,omitempty"` // SHA-256 hash of the licence file
+	LicenceType   string  `json:"licenceType,omitempty"`   // detected licence type, empty if the licence type was not detected
+	Confidence    float64 `json:"confidence,omitempty"`    // confidence of the classifier in the licence type
+}
+
+// Stats describes the entries of a cache.
+type Stats struct {
+	Entries  int            // number of entries
+	Size     int64          // total size of the entries in bytes
+	ByDBHash map[string]int // number of entries for each licence database hash
+	Oldest   time.Time      // last use of the least recently used entry
+	Newest   time.Time      // last use of the most recently used entry
+}
+
+// Cache is a directory of detection results. It can be shared by concurrent runs: entries are written to temporary
+// files that are renamed into place, so readers never see partially written entries.
+type Cache struct {
+	dir string
+}
+
+// DefaultDir returns the go-licence-detector directory in the user cache directory.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user cache directory: %w", err)
+	}
+
+	return filepath.Join(dir, "go-licence-detector"), nil
+}
+
+// Open opens the cache in the given directory, creating it if needed. DefaultDir is used if dir is empty.
+func Open(dir string) (*Cache, error) {
+	if dir == "" {
+		var err error
+		if dir, err = DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+
+	return &Cache{dir: dir}, nil
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the entry of the module version detected with the given licence database, if any. The last use time
+// of the entry is updated so that Prune keeps the entries in use.
+func (c *Cache) Get(module, version, dbHash string) (Entry, bool) {
+	path := c.path(module, version, dbHash)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, false
+	}
+
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Module != module || e.Version != version || e.DBHash != dbHash {
+		return Entry{}, false
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return e, true
+}
+
+// Put stores the entry, replacing any entry of the same module version and licence database.
+func (c *Cache) Put(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry of %s@%s: %w", e.Module, e.Version, err)
+	}
+
+	path := c.path(e.Module, e.Version, e.DBHash)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// rename the complete file into place so that concurrent runs never read a partial entry
+	f, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry of %s@%s: %w", e.Module, e.Version, err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache entry of %s@%s: %w", e.Module, e.Version, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry of %s@%s: %w", e.Module, e.Version, err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to store cache entry of %s@%s: %w", e.Module, e.Version, err)
+	}
+
+	return nil
+}
+
+// Stats returns statistics about the entries of the cache.
+func (c *Cache) Stats() (Stats, error) {
+	stats := Stats{ByDBHash: make(map[string]int)}
+	err := c.walk(func(_ string, info fs.FileInfo, e Entry, ok bool) error {
+		if !ok {
+			return nil
+		}
+
+		stats.Entries++
+		stats.Size += info.Size()
+		stats.ByDBHash[e.DBHash]++
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+		if info.ModTime().After(stats.Newest) {
+			stats.Newest = info.ModTime()
+		}
+
+		return nil
+	})
+
+	return stats, err
+}
+
+// Prune removes the entries for which remove returns true, given the entry and its last use time, as well as invalid
+// entries and the temporary files left behind by interrupted runs. It returns the number of removed entries.
+func (c *Cache) Prune(remove func(e Entry, lastUsed time.Time) bool) (int, error) {
+	removed := 0
+	err := c.walk(func(path string, info fs.FileInfo, e Entry, ok bool) error {
+		switch {
+		case strings.HasSuffix(path, ".tmp"):
+			// temporary files may be written by a concurrent run
+			if time.Since(info.ModTime()) < tempFileAge {
+				return nil
+			}
+		case ok && !remove(e, info.ModTime()):
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove cache entry %s: %w", path, err)
+		}
+
+		if !strings.HasSuffix(path, ".tmp") {
+			removed++
+		}
+		return nil
+	})
+
+	return removed, err
+}
+
+// walk calls fn for each file of the cache with the decoded entry, if the file is a valid entry.
+func (c *Cache) walk(fn func(path string, info fs.FileInfo, e Entry, ok bool) error) error {
+	root := filepath.Join(c.dir, "entries")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// files can be removed by concurrent runs
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		var e Entry
+		ok := false
+		if strings.HasSuffix(path, ".json") {
+			if data, err := os.ReadFile(path); err == nil {
+				ok = json.Unmarshal(data, &e) == nil && e.Module != ""
+			}
+		}
+
+		return fn(path, info, e, ok)
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read cache directory %s: %w", c.dir, err)
+	}
+
+	return nil
+}
+
+// path returns the path of the entry. Entries are spread over subdirectories named after the first byte of the key.
+func (c *Cache) path(module, version, dbHash string) string {
+	sum := sha256.Sum256([]byte(module + "@" + version + "\x00" + dbHash))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, "entries", key[:2], key+".json")
+}
@@ -0,0 +1,149 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPut(t *testing.T) {
+	c, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	entry := Entry{
+		Module:        "github.com/davecgh/go-spew",
+		Version:       "v1.1.1",
+		DBHash:        "db1",
+		LicenceFile:   "LICENSE",
+		LicenceSha256: "abc",
+		LicenceType:   "ISC",
+		Confidence:    0.98,
+	}
+
+	_, ok := c.Get(entry.Module, entry.Version, entry.DBHash)
+	require.False(t, ok)
+
+	require.NoError(t, c.Put(entry))
+
+	have, ok := c.Get(entry.Module, entry.Version, entry.DBHash)
+	require.True(t, ok)
+	require.Equal(t, entry, have)
+
+	// entries are specific to the version and the licence database
+	_, ok = c.Get(entry.Module, "v1.1.0", entry.DBHash)
+	require.False(t, ok)
+	_, ok = c.Get(entry.Module, entry.Version, "db2")
+	require.False(t, ok)
+
+	entry.LicenceType = "MIT"
+	require.NoError(t, c.Put(entry))
+
+	have, ok = c.Get(entry.Module, entry.Version, entry.DBHash)
+	require.True(t, ok)
+	require.Equal(t, "MIT", have.LicenceType)
+}
+
+func TestConcurrentPut(t *testing.T) {
+	dir := t.TempDir()
+
+	// each goroutine opens its own cache like concurrent runs of the detector
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := Open(dir)
+			require.NoError(t, err)
+			for j := range 20 {
+				entry := Entry{Module: fmt.Sprintf("example.com/mod%d", j), Version: "v1.0.0", DBHash: "db", LicenceType: fmt.Sprintf("type%d", i)}
+				require.NoError(t, c.Put(entry))
+				_, ok := c.Get(entry.Module, entry.Version, entry.DBHash)
+				require.True(t, ok)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c, err := Open(dir)
+	require.NoError(t, err)
+
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 20, stats.Entries)
+	require.Equal(t, map[string]int{"db": 20}, stats.ByDBHash)
+}
+
+func TestStatsAndPrune(t *testing.T) {
+	c, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	require.Zero(t, stats.Entries)
+
+	entries := []Entry{
+		{Module: "example.com/a", Version: "v1.0.0", DBHash: "db1", LicenceType: "MIT"},
+		{Module: "example.com/b", Version: "v1.0.0", DBHash: "db1", LicenceType: "MIT"},
+		{Module: "example.com/a", Version: "v1.0.0", DBHash: "db2", LicenceType: "MIT"},
+	}
+	for _, e := range entries {
+		require.NoError(t, c.Put(e))
+	}
+
+	// make the entry of example.com/b look unused for a day
+	old := time.Now().Add(-24 * time.Hour)
+	require.NoError(t, os.Chtimes(c.path("example.com/b", "v1.0.0", "db1"), old, old))
+
+	// invalid entries and stale temporary files are removed
+	dir := filepath.Dir(c.path("example.com/a", "v1.0.0", "db1"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stale.tmp"), nil, 0o644))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "stale.tmp"), old, old))
+
+	stats, err = c.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.Entries)
+	require.Equal(t, map[string]int{"db1": 2, "db2": 1}, stats.ByDBHash)
+	require.WithinDuration(t, old, stats.Oldest, time.Second)
+	require.Positive(t, stats.Size)
+
+	removed, err := c.Prune(func(e Entry, lastUsed time.Time) bool {
+		return e.DBHash != "db1" || time.Since(lastUsed) > time.Hour
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, removed)
+
+	_, ok := c.Get("example.com/a", "v1.0.0", "db1")
+	require.True(t, ok)
+	_, ok = c.Get("example.com/b", "v1.0.0", "db1")
+	require.False(t, ok)
+	_, ok = c.Get("example.com/a", "v1.0.0", "db2")
+	require.False(t, ok)
+	require.NoFileExists(t, filepath.Join(dir, "stale.tmp"))
+
+	stats, err = c.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.Entries)
+}
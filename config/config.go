@@ -0,0 +1,296 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package config reads configuration files written in JSON or YAML that can extend other files.
+package config // import "go.elastic.co/go-licence-detector/config"
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExtendsKey is the key of the documents that names the files or directories extended by a file.
+const ExtendsKey = "extends"
+
+// Extensions lists the file extensions of configuration files. Files in a directory bundle with other extensions are ignored.
+var Extensions = []string{".json", ".yaml", ".yml"}
+
+// Document is a configuration document read from a file.
+type Document struct {
+	Path string          // path of the file containing the document
+	Data json.RawMessage // document encoded as JSON
+}
+
+// Load reads the documents of the files at the given paths, each preceded by the documents of the files it extends.
+// A file can contain several JSON values or YAML documents, and a top-level array is read as one document per
+// element. Any document can name the files it extends with the extends key, either as a single path or a list of
+// paths relative to the directory of the file. Extending a directory extends all the configuration files in it in
+// lexical order. The extends key is removed from the returned documents.
+// Documents of extended files precede the documents of the files extending them, and documents of later paths follow
+// those of earlier paths, so that later documents take precedence. Each file is only read once.
+func Load(paths ...string) ([]Document, error) {
+	l := &loader{loaded: make(map[string]struct{})}
+	for _, path := range paths {
+		if err := l.load(path, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return l.docs, nil
+}
+
+type loader struct {
+	docs   []Document
+	loaded map[string]struct{}
+}
+
+func (l *loader) load(path string, stack []string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to determine absolute path of %s: %w", path, err)
+	}
+
+	if slices.Contains(stack, absPath) {
+		return fmt.Errorf("%s extends itself: %s", path, strings.Join(append(stack, absPath), " -> "))
+	}
+
+	if _, ok := l.loaded[absPath]; ok {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	stack = append(stack, absPath)
+	if info.IsDir() {
+		return l.loadDir(path, stack)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	values, err := decode(path, data)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	// top-level arrays hold one document per element
+	var flattened []any
+	for _, v := range values {
+		if list, ok := v.([]any); ok {
+			flattened = append(flattened, list...)
+			continue
+		}
+		flattened = append(flattened, v)
+	}
+
+	var docs []Document
+	for _, v := range flattened {
+		extends, err := popExtends(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s in %s: %w", ExtendsKey, path, err)
+		}
+
+		for _, e := range extends {
+			if !filepath.IsAbs(e) {
+				e = filepath.Join(filepath.Dir(path), e)
+			}
+
+			if err := l.load(e, stack); err != nil {
+				return err
+			}
+		}
+
+		if m, ok := v.(map[string]any); ok && len(m) == 0 && len(extends) > 0 {
+			continue
+		}
+
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", path, err)
+		}
+
+		docs = append(docs, Document{Path: path, Data: raw})
+	}
+
+	l.loaded[absPath] = struct{}{}
+	l.docs = append(l.docs, docs...)
+
+	return nil
+}
+
+func (l *loader) loadDir(dir string, stack []string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	// entries are sorted by file name
+	for _, e := range entries {
+		if e.IsDir() || !slices.Contains(Extensions, strings.ToLower(filepath.Ext(e.Name()))) {
+			continue
+		}
+
+		if err := l.load(filepath.Join(dir, e.Name()), stack); err != nil {
+			return err
+		}
+	}
+
+	l.loaded[stack[len(stack)-1]] = struct{}{}
+	return nil
+}
+
+// popExtends removes the extends key from the document and returns the paths it names.
+func popExtends(v any) ([]string, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+
+	extends, ok := m[ExtendsKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(m, ExtendsKey)
+
+	switch e := extends.(type) {
+	case string:
+		return []string{e}, nil
+	case []any:
+		paths := make([]string, len(e))
+		for i, p := range e {
+			s, ok := p.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a path but found %v", p)
+			}
+			paths[i] = s
+		}
+		return paths, nil
+	default:
+		return nil, fmt.Errorf("expected a path or a list of paths but found %v", extends)
+	}
+}
+
+// decode decodes the JSON values or YAML documents of the file. YAML is expected if the file has a YAML extension.
+func decode(path string, data []byte) ([]any, error) {
+	var values []any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var node yaml.Node
+			if err := dec.Decode(&node); err != nil {
+				if errors.Is(err, io.EOF) {
+					return values, nil
+				}
+				return nil, err
+			}
+
+			v, err := fromYAML(&node)
+			if err != nil {
+				return nil, err
+			}
+
+			if v != nil {
+				values = append(values, v)
+			}
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		for {
+			var v any
+			if err := dec.Decode(&v); err != nil {
+				if errors.Is(err, io.EOF) {
+					return values, nil
+				}
+				return nil, err
+			}
+			values = append(values, v)
+		}
+	}
+}
+
+// fromYAML converts a YAML node to a value that can be encoded as JSON. Scalars that are not numbers, booleans or
+// nulls, such as dates, are kept as strings.
+func fromYAML(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return fromYAML(node.Content[0])
+	case yaml.AliasNode:
+		return fromYAML(node.Alias)
+	case yaml.SequenceNode:
+		list := make([]any, len(node.Content))
+		for i, n := range node.Content {
+			v, err := fromYAML(n)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case yaml.MappingNode:
+		m := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: mapping keys must be strings", key.Line)
+			}
+
+			v, err := fromYAML(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = v
+		}
+		return m, nil
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!null":
+			return nil, nil
+		case "!!bool":
+			return strconv.ParseBool(strings.ToLower(node.Value))
+		case "!!int", "!!float":
+			var v any
+			if err := node.Decode(&v); err != nil {
+				return nil, err
+			}
+			return v, nil
+		default:
+			return node.Value, nil
+		}
+	default:
+		return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+}
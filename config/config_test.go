@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	docs, err := Load("testdata/team.json")
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+
+	require.Equal(t, filepath.Join("testdata", "org", "base.yaml"), docs[0].Path)
+	require.JSONEq(t, `{"name": "base", "count": 3, "ratio": 0.5, "enabled": true, "date": "2030-06-30", "tags": ["a", "b"]}`, string(docs[0].Data))
+
+	require.Equal(t, "testdata/team.json", docs[1].Path)
+	require.JSONEq(t, `{"name": "team"}`, string(docs[1].Data))
+
+	require.Equal(t, "testdata/team.json", docs[2].Path)
+	require.JSONEq(t, `{"name": "other"}`, string(docs[2].Data))
+}
+
+func TestLoadSeveralPaths(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "list.json")
+	require.NoError(t, os.WriteFile(list, []byte(`[{"extends": "base.yaml", "name": "a"}, {"name": "b"}]`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.yaml"), []byte("name: base\n"), 0o600))
+	other := filepath.Join(dir, "other.yaml")
+	require.NoError(t, os.WriteFile(other, []byte("extends: base.yaml\n---\n- name: c\n"), 0o600))
+
+	docs, err := Load(list, other)
+	require.NoError(t, err)
+	require.Len(t, docs, 4)
+
+	// base.yaml is only read once, when extended by the first file
+	for i, want := range []string{`{"name": "base"}`, `{"name": "a"}`, `{"name": "b"}`, `{"name": "c"}`} {
+		require.JSONEq(t, want, string(docs[i].Data))
+	}
+	require.Equal(t, other, docs[3].Path)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	testCases := map[string]struct {
+		file     string
+		contents string
+	}{
+		"Cycle":           {file: "testdata/cycle-a.yaml"},
+		"MissingExtends":  {file: "rules.yaml", contents: "extends: missing.yaml\n"},
+		"InvalidExtends":  {file: "rules.yaml", contents: "extends: {path: base.yaml}\n"},
+		"InvalidYAML":     {file: "rules.yaml", contents: "allowlist: [MIT\n"},
+		"InvalidJSON":     {file: "rules.json", contents: `{"allowlist": ["MIT"]`},
+		"NonStringKey":    {file: "rules.yaml", contents: "? [a, b]\n: c\n"},
+		"ExtendsDirCycle": {file: "rules.yaml", contents: "extends: .\n"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := tc.file
+			if tc.contents != "" {
+				path = filepath.Join(t.TempDir(), tc.file)
+				require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0o600))
+			}
+
+			_, err := Load(path)
+			require.Error(t, err)
+		})
+	}
+}
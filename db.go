@@ -0,0 +1,96 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"go.elastic.co/go-licence-detector/detector"
+	"go.elastic.co/go-licence-detector/licencedb"
+)
+
+const dbUsage = `Usage: go-licence-detector db <command> [FLAGS]
+
+Commands:
+  build    Build a licence database from a directory of licence texts.
+  list     List the IDs of all licences known to the licence database.
+`
+
+// runDB handles the db subcommand.
+func runDB(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, dbUsage)
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "build":
+		runDBBuild(args[1:])
+	case "list":
+		runDBList(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown db command: %s\n\n%s", args[0], dbUsage)
+		os.Exit(2)
+	}
+}
+
+func runDBBuild(args []string) {
+	fs := flag.NewFlagSet("db build", flag.ExitOnError)
+	dirFlag := fs.String("dir", "", "Directory containing licence texts named <licence ID>.txt (e.g. a checkout of spdx/license-list-data).")
+	outFlag := fs.String("out", "licenses.db", "Path to output the licence database.")
+	includeDeprecatedFlag := fs.Bool("includeDeprecated", false, "Include the texts of deprecated SPDX licence IDs.")
+	_ = fs.Parse(args)
+
+	if *dirFlag == "" {
+		log.Fatal("Directory containing licence texts must be provided with -dir")
+	}
+
+	// build in memory so that a failure does not leave a truncated database behind
+	var buf bytes.Buffer
+	if err := licencedb.BuildFromDir(*dirFlag, &buf, licencedb.BuildOptions{IncludeDeprecated: *includeDeprecatedFlag}); err != nil {
+		log.Fatalf("Failed to build licence database: %v", err)
+	}
+
+	if err := os.WriteFile(*outFlag, buf.Bytes(), 0o644); err != nil {
+		log.Fatalf("Failed to write licence database: %v", err)
+	}
+}
+
+func runDBList(args []string) {
+	fs := flag.NewFlagSet("db list", flag.ExitOnError)
+	licenceDataFlag := fs.String("licenceData", "", "Path to the licence database. Uses embedded database if empty.")
+	_ = fs.Parse(args)
+
+	licenceDB, err := detector.LoadLicenceDB(*licenceDataFlag)
+	if err != nil {
+		log.Fatalf("Failed to load licence database: %v", err)
+	}
+
+	ids, err := licencedb.List(bytes.NewReader(licenceDB))
+	if err != nil {
+		log.Fatalf("Failed to list licences: %v", err)
+	}
+
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+}
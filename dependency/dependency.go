@@ -18,20 +18,49 @@
 package dependency // import "go.elastic.co/go-licence-detector/dependency"
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
-	"os"
+	"maps"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
+	"go.elastic.co/go-licence-detector/config"
+	"go.elastic.co/go-licence-detector/licence"
+)
+
+// Contexts in which a dependency can be used by the main module.
+const (
+	ContextDirect   = "direct"   // required directly by the main module
+	ContextIndirect = "indirect" // only required by other dependencies
+	ContextTest     = "test"     // only reachable from tests
+	ContextTool     = "tool"     // only provides tools declared with tool directives in go.mod
+)
+
+// Contexts lists all dependency contexts.
+var Contexts = []string{ContextDirect, ContextIndirect, ContextTest, ContextTool}
+
+// Sources of the licence type of a dependency.
+const (
+	LicenceSourceOverride     = "override"      // licence type given by an override
+	LicenceSourceOverrideFile = "override-file" // detected from the licence file or text given by an override
+	LicenceSourceFile         = "file"          // detected from the licence file found in the module
+	LicenceSourceReadme       = "readme"        // detected from the README of a module without a licence file
 )
 
 // List holds direct and indirect dependency information.
 type List struct {
-	Direct   []Info
-	Indirect []Info
+	OutboundLicence string          `json:"outboundLicence,omitempty"` // licence under which the main module is distributed
+	Direct          []Info          `json:"direct"`
+	Indirect        []Info          `json:"indirect"`
+	Exceptions      []Exception     `json:"exceptions,omitempty"`     // licence exceptions used by the dependencies
+	PendingReviews  []PendingReview `json:"pendingReviews,omitempty"` // dependencies with maybelisted licences that have not been approved
+	Excluded        []Excluded      `json:"excluded,omitempty"`       // dependencies excluded by overrides
+	Warnings        []string        `json:"warnings,omitempty"`       // problems that do not fail the detection
 }
 
 // Info holds information about a dependency.
@@ -40,55 +69,203 @@ type Info struct {
 	Dir                     string `json:"-"`
 	LicenceFile             string `json:"licenceFile"`
 	LicenceType             string `json:"licenceType"`
+	LicenceCategory         string `json:"licenceCategory"`
 	URL                     string `json:"url"`
 	Version                 string `json:"version"`
 	VersionTime             string `json:"versionTime"`
 	LicenceTextOverrideFile string `json:"licenceTextOverrideFile"`
 	LocalReplacement        bool   `json:"-"`
+
+	// Versions is the version range that an override applies to. Empty matches all versions.
+	Versions string `json:"versions,omitempty"`
+	// LicenceSha256 is the hex-encoded SHA-256 hash of the licence file that an override was written for.
+	LicenceSha256 string `json:"licenceSha256,omitempty"`
+	// LicenceText is the licence text given inline by an override instead of a licence file.
+	LicenceText string `json:"licenceText,omitempty"`
+	// OverrideSources maps the fields set by overrides to the overrides file that set them.
+	OverrideSources map[string]string `json:"overrideSources,omitempty"`
+	// Override is the key of the override that applied to the dependency, if any (see Overrides.Find).
+	Override string `json:"override,omitempty"`
+	// Exclude is true if an override excludes the dependency from the dependency lists. Reason must explain why.
+	Exclude bool `json:"exclude,omitempty"`
+	// Reason explains why the override is needed.
+	Reason string `json:"reason,omitempty"`
+	// ReviewedBy identifies who reviewed the override.
+	ReviewedBy string `json:"reviewedBy,omitempty"`
+	// ReviewedAt is the date (YYYY-MM-DD) on which the override was reviewed.
+	ReviewedAt string `json:"reviewedAt,omitempty"`
+	// Ticket references the issue or pull request that added the override.
+	Ticket string `json:"ticket,omitempty"`
+	// Annotations holds free-form notes of the override, such as modifications made to the dependency.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Warnings holds problems found with the dependency that do not fail the detection.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Context describes how the dependency is used by the main module (direct, indirect, test or tool).
+	Context string `json:"context"`
+	// LicenceSource describes where the licence type came from (see the LicenceSource constants).
+	LicenceSource string `json:"licenceSource"`
+	// LicenceConfidence is the confidence of the licence classifier in the detected licence type.
+	LicenceConfidence float64 `json:"licenceConfidence,omitempty"`
+	// Replaced is true if the module is replaced by another module or a local directory.
+	Replaced bool `json:"replaced,omitempty"`
+	// PolicyResults holds the results of the policies of the rules file that matched the dependency.
+	PolicyResults []PolicyResult `json:"policyResults,omitempty"`
+
+	// Exception is the licence exception from the rules file that allowed this dependency, if any.
+	Exception *Exception `json:"exception,omitempty"`
+	// Approval is the recorded review that allowed the maybelisted licence of this dependency, if any.
+	Approval *Approval `json:"approval,omitempty"`
+	// ReviewRequired is true if the dependency uses a maybelisted licence that has not been approved.
+	ReviewRequired bool `json:"reviewRequired,omitempty"`
+}
+
+// Exception is a documented and time-limited permission for a module to use a licence which is not otherwise allowed.
+type Exception struct {
+	Module        string `json:"module"`
+	Licence       string `json:"licence"`
+	Justification string `json:"justification"`
+	Approver      string `json:"approver"`
+	Expires       string `json:"expires"` // last day (YYYY-MM-DD) on which the exception is valid
+}
+
+// Approval records the review of a maybelisted licence for a range of module versions.
+type Approval struct {
+	Module   string `json:"module"`
+	Versions string `json:"versions,omitempty"` // version range (e.g. ">= v1.2.0, < v2.0.0"). Empty matches all versions.
+	Licence  string `json:"licence"`
+	Reviewer string `json:"reviewer"`
+	Date     string `json:"date"` // date (YYYY-MM-DD) of the review
+	Comment  string `json:"comment,omitempty"`
+}
+
+// PolicyResult is the outcome of a policy of the rules file that matched a dependency.
+type PolicyResult struct {
+	Policy   string `json:"policy"`
+	Severity string `json:"severity"` // error, warn or info
+	Message  string `json:"message"`
+}
+
+// Excluded identifies a dependency excluded from the dependency lists by an override.
+type Excluded struct {
+	Module   string `json:"module"`
+	Version  string `json:"version"`
+	Override string `json:"override"` // key of the override that excluded the dependency
+	Reason   string `json:"reason"`
 }
 
-// Overrides is a mapping from module name to dependency info.
+// PendingReview identifies a dependency with a maybelisted licence that has not been approved yet.
+type PendingReview struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Licence string `json:"licence"`
+}
+
+// Overrides is a mapping from module name to dependency info. Keys can be scoped to a version or a version range
+// (e.g. example.com/a@v1.2.0) and module names can be path globs (e.g. cloud.google.com/go/*).
 type Overrides map[string]Info
 
-// LoadOverrides loads the dependency overrides from the given file.
-// LicenceTextOverrideFile will be read relative to the parent directory of the file.
-func LoadOverrides(file string) (Overrides, error) {
+// LoadOverrides loads the dependency overrides from the given files. The files can be written as newline-delimited
+// JSON, JSON arrays or YAML and extend other override files or directories of override files. Overrides of the same
+// module are merged field by field, with the fields set by later files, and by files over the files they extend,
+// taking precedence. The file that set each field is recorded in OverrideSources.
+// Licence types are validated against the SPDX licence list and normalised to canonical identifiers.
+// LicenceTextOverrideFile will be read relative to the parent directory of the file declaring the override.
+func LoadOverrides(files ...string) (Overrides, error) {
 	depMap := make(Overrides)
-	if file == "" {
+	files = slices.DeleteFunc(slices.Clone(files), func(file string) bool { return file == "" })
+	if len(files) == 0 {
 		return depMap, nil
 	}
 
-	f, err := os.Open(file)
+	docs, err := config.Load(files...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open overrides file %s: %w", file, err)
+		return nil, fmt.Errorf("failed to load overrides files %s: %w", strings.Join(files, ", "), err)
 	}
-	defer f.Close()
 
-	rootDir, err := filepath.Abs(filepath.Dir(file))
-	if err != nil {
-		return nil, fmt.Errorf("failed to determine absolute path of overrides file: %w", err)
+	for _, doc := range docs {
+		dep, err := loadOverride(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		key := OverrideKey(dep)
+		if base, ok := depMap[key]; ok {
+			dep = mergeOverride(base, dep)
+		}
+		depMap[key] = dep
 	}
 
-	decoder := json.NewDecoder(f)
-	for {
-		var dep Info
-		err := decoder.Decode(&dep)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				return depMap, nil
+	// the reason of an exclusion can be given by another file than the exclusion itself
+	for _, key := range slices.Sorted(maps.Keys(depMap)) {
+		if dep := depMap[key]; dep.Exclude && strings.TrimSpace(dep.Reason) == "" {
+			return nil, fmt.Errorf("override %s in %s excludes the module without a reason", dep.Name, dep.OverrideSources["exclude"])
+		}
+	}
+
+	return depMap, nil
+}
+
+// loadOverride reads and validates the override of the document.
+func loadOverride(doc config.Document) (Info, error) {
+	var dep Info
+	if err := json.Unmarshal(doc.Data, &dep); err != nil {
+		return dep, fmt.Errorf("error reading dependency information from %s: %w", doc.Path, err)
+	}
+
+	for field, value := range dep.overrideFields() {
+		if field != "name" && field != "versions" && isSet(value) {
+			if dep.OverrideSources == nil {
+				dep.OverrideSources = make(map[string]string)
 			}
+			dep.OverrideSources[field] = doc.Path
+		}
+	}
 
-			return depMap, fmt.Errorf("error reading dependency information: %w", err)
+	var err error
+	if dep.LicenceType != "" {
+		if dep.LicenceType, err = licence.NormaliseExpression(dep.LicenceType); err != nil {
+			return dep, fmt.Errorf("invalid licence type of %s in %s: %w", dep.Name, doc.Path, err)
 		}
+	}
 
-		if dep.LicenceTextOverrideFile != "" {
-			licFile, err := securejoin.SecureJoin(rootDir, dep.LicenceTextOverrideFile)
-			if err != nil {
-				return nil, fmt.Errorf("failed to generate secure path to licence text file of %s: %w", dep.Name, err)
-			}
-			dep.LicenceFile = licFile
+	if dep.ReviewedAt != "" {
+		if _, err := time.Parse(time.DateOnly, dep.ReviewedAt); err != nil {
+			return dep, fmt.Errorf("invalid review date of %s in %s: must be in YYYY-MM-DD format: %w", dep.Name, doc.Path, err)
+		}
+	}
+
+	if dep.LicenceSha256 != "" {
+		if sum, err := hex.DecodeString(dep.LicenceSha256); err != nil || len(sum) != sha256.Size {
+			return dep, fmt.Errorf("invalid licence hash of %s in %s: must be a hex-encoded SHA-256 hash", dep.Name, doc.Path)
 		}
+		dep.LicenceSha256 = strings.ToLower(dep.LicenceSha256)
+	}
 
-		depMap[dep.Name] = dep
+	if dep.LicenceText != "" && (dep.LicenceFile != "" || dep.LicenceTextOverrideFile != "") {
+		return dep, fmt.Errorf("override %s in %s must not specify both a licence text and a licence file", dep.Name, doc.Path)
 	}
+
+	if dep.LicenceTextOverrideFile != "" {
+		rootDir, err := filepath.Abs(filepath.Dir(doc.Path))
+		if err != nil {
+			return dep, fmt.Errorf("failed to determine absolute path of overrides file: %w", err)
+		}
+
+		licFile, err := securejoin.SecureJoin(rootDir, dep.LicenceTextOverrideFile)
+		if err != nil {
+			return dep, fmt.Errorf("failed to generate secure path to licence text file of %s: %w", dep.Name, err)
+		}
+		dep.LicenceFile = licFile
+	}
+
+	if dep.Versions != "" && strings.Contains(dep.Name, "@") {
+		return dep, fmt.Errorf("override %s in %s must not specify both a version in the name and versions", dep.Name, doc.Path)
+	}
+
+	if _, err := parseOverrideKey(OverrideKey(dep)); err != nil {
+		return dep, fmt.Errorf("invalid override in %s: %w", doc.Path, err)
+	}
+
+	return dep, nil
 }
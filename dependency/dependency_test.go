@@ -18,7 +18,11 @@
 package dependency
 
 import (
+	"encoding/json"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -49,3 +53,169 @@ func TestLoadOverrides(t *testing.T) {
 	require.Equal(t, o4LicencePath, o4.LicenceFile)
 	require.Empty(t, o4.LicenceType)
 }
+
+func TestLoadOverridesLicenceType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "my.pkg/v1", "licenceType": "GPL-2.0 OR Public Domain"}`), 0o600))
+
+	overrides, err := LoadOverrides(path)
+	require.NoError(t, err)
+	require.Equal(t, "GPL-2.0-only OR LicenseRef-Public-Domain", overrides["my.pkg/v1"].LicenceType)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "my.pkg/v1", "licenceType": "Apache 2"}`), 0o600))
+	_, err = LoadOverrides(path)
+	require.ErrorContains(t, err, `unknown licence ID "Apache 2". Did you mean "Apache-2.0"?`)
+}
+
+func TestLoadOverridesLicenceSha256(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	hash := "0783BA14AE06CC4AE3AA57F19D9C59A3346532A6E5200E61722DB83BD3D74D0B"
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "my.pkg/v1", "licenceSha256": "`+hash+`"}`), 0o600))
+
+	overrides, err := LoadOverrides(path)
+	require.NoError(t, err)
+	require.Equal(t, strings.ToLower(hash), overrides["my.pkg/v1"].LicenceSha256)
+
+	for _, invalid := range []string{"0783ba14", hash + "00", "sha256:" + hash} {
+		require.NoError(t, os.WriteFile(path, []byte(`{"name": "my.pkg/v1", "licenceSha256": "`+invalid+`"}`), 0o600))
+		_, err = LoadOverrides(path)
+		require.ErrorContains(t, err, "invalid licence hash of my.pkg/v1", invalid)
+	}
+}
+
+func TestLoadOverridesExclude(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "example.com/*", "exclude": true, "reason": "Test fixtures"}`), 0o600))
+
+	overrides, err := LoadOverrides(path)
+	require.NoError(t, err)
+	require.True(t, overrides["example.com/*"].Exclude)
+	require.Equal(t, "Test fixtures", overrides["example.com/*"].Reason)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "example.com/*", "exclude": true, "reason": " "}`), 0o600))
+	_, err = LoadOverrides(path)
+	require.ErrorContains(t, err, "override example.com/* in "+path+" excludes the module without a reason")
+}
+
+func TestLoadOverridesMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.yaml")
+	content := `
+name: my.pkg/v1
+licenceType: MIT
+reason: The licence file is missing from the module.
+reviewedBy: legal@example.com
+reviewedAt: 2024-02-29
+ticket: https://github.com/elastic/example/issues/1
+annotations:
+  modified: Modified by Elastic, see patch
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	overrides, err := LoadOverrides(path)
+	require.NoError(t, err)
+
+	o := overrides["my.pkg/v1"]
+	require.Equal(t, "The licence file is missing from the module.", o.Reason)
+	require.Equal(t, "legal@example.com", o.ReviewedBy)
+	require.Equal(t, "2024-02-29", o.ReviewedAt)
+	require.Equal(t, "https://github.com/elastic/example/issues/1", o.Ticket)
+	require.Equal(t, map[string]string{"modified": "Modified by Elastic, see patch"}, o.Annotations)
+
+	require.NoError(t, os.WriteFile(path, []byte("name: my.pkg/v1\nreviewedAt: 29/02/2024\n"), 0o600))
+	_, err = LoadOverrides(path)
+	require.ErrorContains(t, err, "invalid review date of my.pkg/v1")
+}
+
+func TestLoadOverridesExtends(t *testing.T) {
+	overrides, err := LoadOverrides("testdata/team/overrides.yaml")
+	require.NoError(t, err)
+	require.Len(t, overrides, 5)
+
+	require.Equal(t, "MIT", overrides["my.pkg/v1"].LicenceType)
+	require.Equal(t, "https://me.example.com/pkg", overrides["my.otherpkg/v1"].URL)
+
+	baseLicencePath, err := filepath.Abs("./testdata/my/securepkg/v1/licence.txt")
+	require.NoError(t, err)
+	require.Equal(t, baseLicencePath, overrides["my.securepkg/v1"].LicenceFile)
+
+	teamLicencePath, err := filepath.Abs("./testdata/team/licence.txt")
+	require.NoError(t, err)
+	require.Equal(t, teamLicencePath, overrides["my.teampkg/v1"].LicenceFile)
+}
+
+func TestLoadOverridesLayered(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "licence.txt"), []byte("Shared licence"), 0o600))
+
+	shared := filepath.Join(dir, "shared.json")
+	require.NoError(t, os.WriteFile(shared, []byte(`[
+  {"name": "my.pkg/v1", "licenceType": "MIT", "url": "https://example.com/pkg", "licenceTextOverrideFile": "licence.txt", "annotations": {"a": "shared", "b": "shared"}},
+  {"name": "my.otherpkg/v1", "exclude": true}
+]`), 0o600))
+
+	team := filepath.Join(dir, "team.yaml")
+	require.NoError(t, os.WriteFile(team, []byte(`
+name: my.pkg/v1
+licenceText: Team licence
+annotations:
+  b: team
+---
+name: my.otherpkg/v1
+reason: Test fixtures
+`), 0o600))
+
+	overrides, err := LoadOverrides(shared, "", team)
+	require.NoError(t, err)
+	require.Len(t, overrides, 2)
+
+	o1 := overrides["my.pkg/v1"]
+	require.Equal(t, "MIT", o1.LicenceType)
+	require.Equal(t, "https://example.com/pkg", o1.URL)
+	require.Equal(t, "Team licence", o1.LicenceText)
+	require.Empty(t, o1.LicenceTextOverrideFile)
+	require.Empty(t, o1.LicenceFile)
+	require.Equal(t, map[string]string{"a": "shared", "b": "team"}, o1.Annotations)
+	require.Equal(t, map[string]string{
+		"licenceType": shared,
+		"url":         shared,
+		"licenceText": team,
+		"annotations": team,
+	}, o1.OverrideSources)
+
+	o2 := overrides["my.otherpkg/v1"]
+	require.True(t, o2.Exclude)
+	require.Equal(t, "Test fixtures", o2.Reason)
+	require.Equal(t, map[string]string{"exclude": shared, "reason": team}, o2.OverrideSources)
+
+	// the exclusion needs a reason once all files are merged
+	_, err = LoadOverrides(shared)
+	require.ErrorContains(t, err, "override my.otherpkg/v1 in "+shared+" excludes the module without a reason")
+
+	require.NoError(t, os.WriteFile(team, []byte("name: my.pkg/v1\nlicenceText: Team licence\nlicenceFile: LICENSE\n"), 0o600))
+	_, err = LoadOverrides(shared, team)
+	require.ErrorContains(t, err, "must not specify both a licence text and a licence file")
+}
+
+func TestOverridesSchema(t *testing.T) {
+	data, err := os.ReadFile("../schema/overrides.schema.json")
+	require.NoError(t, err)
+
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	fields := make(map[string]struct{})
+	typ := reflect.TypeOf(Info{})
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		fields[name] = struct{}{}
+	}
+
+	for name := range schema.Properties {
+		if name == "extends" {
+			continue
+		}
+		require.Contains(t, fields, name, "schema property %s is not a field of the dependency information", name)
+	}
+}
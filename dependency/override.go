@@ -0,0 +1,343 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package dependency
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// Precedence of overrides matching a module version, from the most to the least specific.
+const (
+	precedenceExactVersion = iota
+	precedenceVersionRange
+	precedenceModule
+	precedenceGlobExactVersion
+	precedenceGlobVersionRange
+	precedenceGlob
+)
+
+// overrideEntry is a parsed override key.
+type overrideEntry struct {
+	key      string
+	pattern  string // module path or path glob
+	glob     bool
+	versions VersionRange
+}
+
+// OverrideKey returns the key of the override in Overrides. Overrides scoped to a version range are keyed by the name
+// and the range separated by "@".
+func OverrideKey(override Info) string {
+	if override.Versions != "" {
+		return override.Name + "@" + override.Versions
+	}
+
+	return override.Name
+}
+
+// parseOverrideKey parses an override key of the form path[@versions], where path is a module path or a path glob
+// and versions is an exact version or a version range.
+func parseOverrideKey(key string) (overrideEntry, error) {
+	pattern, versions, _ := strings.Cut(key, "@")
+	if pattern == "" {
+		return overrideEntry{}, fmt.Errorf("override %q must specify the module path", key)
+	}
+
+	if strings.Contains(pattern, ",") {
+		return overrideEntry{}, fmt.Errorf("override %q must specify a single module path", key)
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return overrideEntry{}, fmt.Errorf("override %q has an invalid module path: %w", key, err)
+	}
+
+	vr, err := ParseVersionRange(versions)
+	if err != nil {
+		return overrideEntry{}, fmt.Errorf("override %q has an invalid version range: %w", key, err)
+	}
+
+	return overrideEntry{key: key, pattern: pattern, glob: strings.ContainsAny(pattern, "*?["), versions: vr}, nil
+}
+
+func (e overrideEntry) precedence() int {
+	p := precedenceModule
+	switch {
+	case e.versions.IsExact():
+		p = precedenceExactVersion
+	case !e.versions.IsAny():
+		p = precedenceVersionRange
+	}
+
+	if e.glob {
+		p += precedenceGlobExactVersion
+	}
+
+	return p
+}
+
+func (e overrideEntry) matchesPath(modulePath string) bool {
+	if e.glob {
+		return module.MatchPrefixPatterns(e.pattern, modulePath)
+	}
+
+	return e.pattern == modulePath
+}
+
+// Find returns the override that applies to the given version of the module. If several overrides match, the most
+// specific one applies, in the following order:
+//
+//  1. The module path with an exact version (e.g. example.com/a@v1.2.0).
+//  2. The module path with a version range (e.g. example.com/a@>=v1.2.0).
+//  3. The module path without a version.
+//  4. A path glob with an exact version, a path glob with a version range and a path glob without a version.
+//     Longer globs take precedence over shorter ones.
+//
+// The Override field of the returned override is set to its key. Overrides with invalid keys never match.
+// LoadOverrides rejects them.
+func (o Overrides) Find(modulePath, version string) (Info, bool) {
+	var best *overrideEntry
+	for key := range o {
+		e, err := parseOverrideKey(key)
+		if err != nil || !e.matchesPath(modulePath) || !e.versions.Contains(version) {
+			continue
+		}
+
+		if best == nil || compareOverrideEntries(e, *best) < 0 {
+			best = &e
+		}
+	}
+
+	if best == nil {
+		return Info{}, false
+	}
+
+	override := o[best.key]
+	override.Override = best.key
+	return override, true
+}
+
+func compareOverrideEntries(a, b overrideEntry) int {
+	return cmp.Or(
+		cmp.Compare(a.precedence(), b.precedence()),
+		cmp.Compare(len(b.pattern), len(a.pattern)),
+		strings.Compare(a.key, b.key),
+	)
+}
+
+// Mismatched returns the keys of the overrides of the module path that are scoped to other versions than the given
+// version. They are only returned if none of the version-scoped overrides of the module path applies to the version,
+// which usually means that the overrides were written before the module was upgraded.
+func (o Overrides) Mismatched(modulePath, version string) []string {
+	var mismatched []string
+	for key := range o {
+		e, err := parseOverrideKey(key)
+		if err != nil || e.glob || e.pattern != modulePath || e.versions.IsAny() {
+			continue
+		}
+
+		if e.versions.Contains(version) {
+			return nil
+		}
+
+		mismatched = append(mismatched, key)
+	}
+
+	slices.Sort(mismatched)
+	return mismatched
+}
+
+// IsEmptyOverride returns true if the override does not change any information of the dependency. The audit metadata
+// of the override is not taken into account.
+func (i Info) IsEmptyOverride() bool {
+	return !i.Exclude && i.LicenceType == "" && i.LicenceFile == "" && i.LicenceTextOverrideFile == "" &&
+		i.LicenceText == "" && i.LicenceSha256 == "" && i.URL == "" && i.Version == "" && i.VersionTime == "" &&
+		len(i.Annotations) == 0
+}
+
+// licenceTextFields are the fields of an override that give the licence file or text of a module. An override that
+// sets any of them replaces all of them when merged.
+var licenceTextFields = []string{"licenceFile", "licenceTextOverrideFile", "licenceText"}
+
+// overrideFields returns pointers to the fields of the override, keyed by their JSON names.
+func (i *Info) overrideFields() map[string]any {
+	return map[string]any{
+		"name":                    &i.Name,
+		"versions":                &i.Versions,
+		"exclude":                 &i.Exclude,
+		"reason":                  &i.Reason,
+		"reviewedBy":              &i.ReviewedBy,
+		"reviewedAt":              &i.ReviewedAt,
+		"ticket":                  &i.Ticket,
+		"annotations":             &i.Annotations,
+		"licenceType":             &i.LicenceType,
+		"licenceFile":             &i.LicenceFile,
+		"licenceTextOverrideFile": &i.LicenceTextOverrideFile,
+		"licenceText":             &i.LicenceText,
+		"licenceSha256":           &i.LicenceSha256,
+		"url":                     &i.URL,
+		"version":                 &i.Version,
+		"versionTime":             &i.VersionTime,
+	}
+}
+
+func isSet(field any) bool {
+	switch f := field.(type) {
+	case *string:
+		return *f != ""
+	case *bool:
+		return *f
+	case *map[string]string:
+		return len(*f) > 0
+	default:
+		return false
+	}
+}
+
+// mergeOverride returns the override with the fields set by the layer replacing those of the base. Annotations are
+// merged by key.
+func mergeOverride(base, layer Info) Info {
+	merged := base
+	merged.Annotations = maps.Clone(base.Annotations)
+	merged.OverrideSources = maps.Clone(base.OverrideSources)
+	if merged.OverrideSources == nil {
+		merged.OverrideSources = make(map[string]string)
+	}
+
+	mergedFields, layerFields := merged.overrideFields(), layer.overrideFields()
+	if slices.ContainsFunc(licenceTextFields, func(name string) bool { return isSet(layerFields[name]) }) {
+		for _, name := range licenceTextFields {
+			*mergedFields[name].(*string) = ""
+			delete(merged.OverrideSources, name)
+		}
+	}
+
+	for name, field := range layerFields {
+		if !isSet(field) {
+			continue
+		}
+
+		switch f := field.(type) {
+		case *string:
+			*mergedFields[name].(*string) = *f
+		case *bool:
+			*mergedFields[name].(*bool) = *f
+		case *map[string]string:
+			m := mergedFields[name].(*map[string]string)
+			if *m == nil {
+				*m = make(map[string]string, len(*f))
+			}
+			maps.Copy(*m, *f)
+		}
+
+		if source, ok := layer.OverrideSources[name]; ok {
+			merged.OverrideSources[name] = source
+		}
+	}
+
+	return merged
+}
+
+// overrideRecord holds the fields of an override written to an overrides file.
+type overrideRecord struct {
+	Name                    string            `json:"name"`
+	Versions                string            `json:"versions,omitempty"`
+	Exclude                 bool              `json:"exclude,omitempty"`
+	Reason                  string            `json:"reason,omitempty"`
+	ReviewedBy              string            `json:"reviewedBy,omitempty"`
+	ReviewedAt              string            `json:"reviewedAt,omitempty"`
+	Ticket                  string            `json:"ticket,omitempty"`
+	Annotations             map[string]string `json:"annotations,omitempty"`
+	LicenceType             string            `json:"licenceType,omitempty"`
+	LicenceFile             string            `json:"licenceFile,omitempty"`
+	LicenceTextOverrideFile string            `json:"licenceTextOverrideFile,omitempty"`
+	LicenceText             string            `json:"licenceText,omitempty"`
+	LicenceSha256           string            `json:"licenceSha256,omitempty"`
+	URL                     string            `json:"url,omitempty"`
+	Version                 string            `json:"version,omitempty"`
+	VersionTime             string            `json:"versionTime,omitempty"`
+}
+
+// WriteOverrides writes the overrides to the given file as newline-delimited JSON, sorted by key. Licence text
+// override files are written relative to the directory of the file, so overrides loaded from several layered files
+// can be written to a single file as long as the licence text override files are in its directory.
+func WriteOverrides(file string, overrides Overrides) error {
+	rootDir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return fmt.Errorf("failed to determine absolute path of overrides file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	for _, key := range slices.Sorted(maps.Keys(overrides)) {
+		override := overrides[key]
+		record := overrideRecord{
+			Name:                    override.Name,
+			Versions:                override.Versions,
+			Exclude:                 override.Exclude,
+			Reason:                  override.Reason,
+			ReviewedBy:              override.ReviewedBy,
+			ReviewedAt:              override.ReviewedAt,
+			Ticket:                  override.Ticket,
+			Annotations:             override.Annotations,
+			LicenceType:             override.LicenceType,
+			LicenceFile:             override.LicenceFile,
+			LicenceTextOverrideFile: override.LicenceTextOverrideFile,
+			LicenceText:             override.LicenceText,
+			LicenceSha256:           override.LicenceSha256,
+			URL:                     override.URL,
+			Version:                 override.Version,
+			VersionTime:             override.VersionTime,
+		}
+
+		// LoadOverrides resolves the licence text override file to the licence file
+		if override.LicenceTextOverrideFile != "" {
+			record.LicenceFile = ""
+			if record.LicenceTextOverrideFile, err = filepath.Rel(rootDir, override.LicenceFile); err != nil {
+				return fmt.Errorf("failed to determine path of licence text override file of %s: %w", key, err)
+			}
+
+			// LoadOverrides does not allow licence text override files outside of the directory of the overrides file
+			if !filepath.IsLocal(record.LicenceTextOverrideFile) {
+				return fmt.Errorf("licence text override file %s of %s is not in the directory of %s", override.LicenceFile, key, file)
+			}
+			record.LicenceTextOverrideFile = filepath.ToSlash(record.LicenceTextOverrideFile)
+		}
+
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode override %s: %w", key, err)
+		}
+	}
+
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write overrides file %s: %w", file, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package dependency
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverridesFind(t *testing.T) {
+	overrides := Overrides{
+		"cloud.google.com/go/*":                   {URL: "glob"},
+		"cloud.google.com/go/*@< v0.50.0":         {URL: "glob range"},
+		"cloud.google.com/go/storage":             {URL: "module"},
+		"cloud.google.com/go/storage@>= v1.10.0":  {URL: "module range"},
+		"cloud.google.com/go/storage@v1.10.0":     {URL: "module version"},
+		"cloud.google.com/go/pubsub/*":            {URL: "longer glob"},
+		"example.com/[invalid":                    {URL: "invalid"},
+		"example.com/a@>= v1.0.0, < v2.0.0":       {URL: "a range"},
+		"example.com/a@>= v1.5.0, < v2.0.0":       {URL: "a narrower range"},
+		"example.com/b@v1.0.0":                    {URL: "b version"},
+		"github.com/elastic/go-licence-detector":  {URL: "exact path"},
+		"github.com/elastic/go-licence-detector*": {URL: "glob does not cross path separators"},
+	}
+
+	testCases := []struct {
+		modulePath string
+		version    string
+		wantKey    string
+	}{
+		{modulePath: "cloud.google.com/go/storage", version: "v1.10.0", wantKey: "cloud.google.com/go/storage@v1.10.0"},
+		{modulePath: "cloud.google.com/go/storage", version: "v1.11.0", wantKey: "cloud.google.com/go/storage@>= v1.10.0"},
+		{modulePath: "cloud.google.com/go/storage", version: "v1.9.0", wantKey: "cloud.google.com/go/storage"},
+		{modulePath: "cloud.google.com/go/bigquery", version: "v0.49.0", wantKey: "cloud.google.com/go/*@< v0.50.0"},
+		{modulePath: "cloud.google.com/go/bigquery", version: "v1.0.0", wantKey: "cloud.google.com/go/*"},
+		{modulePath: "cloud.google.com/go/bigquery/v2", version: "v2.0.0", wantKey: "cloud.google.com/go/*"},
+		{modulePath: "cloud.google.com/go/pubsub/v2", version: "v2.0.0", wantKey: "cloud.google.com/go/pubsub/*"},
+		{modulePath: "example.com/a", version: "v1.6.0", wantKey: "example.com/a@>= v1.0.0, < v2.0.0"},
+		{modulePath: "example.com/a", version: "v2.0.0"},
+		{modulePath: "example.com/b", version: "v1.0.1"},
+		{modulePath: "github.com/elastic/go-licence-detector", version: "v1.0.0", wantKey: "github.com/elastic/go-licence-detector"},
+		{modulePath: "example.com/[invalid", version: "v1.0.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.modulePath+"@"+tc.version, func(t *testing.T) {
+			got, ok := overrides.Find(tc.modulePath, tc.version)
+			if tc.wantKey == "" {
+				require.False(t, ok, "unexpected override %s", got.Override)
+				return
+			}
+
+			require.True(t, ok)
+			require.Equal(t, tc.wantKey, got.Override)
+			require.Equal(t, overrides[tc.wantKey].URL, got.URL)
+		})
+	}
+}
+
+func TestOverridesMismatched(t *testing.T) {
+	overrides := Overrides{
+		"example.com/a":          {},
+		"example.com/a@v1.0.0":   {},
+		"example.com/a@< v1.0.0": {},
+		"example.com/*@v2.0.0":   {},
+		"example.com/b":          {},
+	}
+
+	require.Equal(t, []string{"example.com/a@< v1.0.0", "example.com/a@v1.0.0"}, overrides.Mismatched("example.com/a", "v1.1.0"))
+	require.Nil(t, overrides.Mismatched("example.com/a", "v0.9.0"))
+	require.Nil(t, overrides.Mismatched("example.com/b", "v2.1.0"))
+	require.Nil(t, overrides.Mismatched("example.com/c", "v1.0.0"))
+}
+
+func TestLoadOverridesInvalidKeys(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "VersionInNameAndVersions", content: `{"name": "example.com/a@v1.0.0", "versions": ">= v1.0.0"}`},
+		{name: "InvalidVersion", content: `{"name": "example.com/a@latest"}`},
+		{name: "InvalidVersions", content: `{"name": "example.com/a", "versions": ">= latest"}`},
+		{name: "InvalidGlob", content: `{"name": "example.com/[a"}`},
+		{name: "SeveralPaths", content: `{"name": "example.com/a,example.com/b"}`},
+		{name: "MissingPath", content: `{"name": "@v1.0.0"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "overrides.json")
+			require.NoError(t, os.WriteFile(file, []byte(tc.content), 0o600))
+
+			_, err := LoadOverrides(file)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestLoadOverridesVersioned(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "overrides.json")
+	content := `
+{"name": "example.com/a", "licenceType": "MIT"}
+{"name": "example.com/a@v1.0.0", "licenceType": "Apache-2.0"}
+{"name": "example.com/a", "versions": ">= v2.0.0", "licenceType": "BSD-3-Clause"}
+`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+
+	overrides, err := LoadOverrides(file)
+	require.NoError(t, err)
+	require.Len(t, overrides, 3)
+
+	for version, want := range map[string]string{"v0.9.0": "MIT", "v1.0.0": "Apache-2.0", "v2.1.0": "BSD-3-Clause"} {
+		got, ok := overrides.Find("example.com/a", version)
+		require.True(t, ok)
+		require.Equal(t, want, got.LicenceType, version)
+	}
+}
+
+func TestWriteOverrides(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "licences"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "licences", "a.txt"), []byte("MIT License"), 0o600))
+
+	content := `
+{"name": "example.com/a", "licenceTextOverrideFile": "licences/a.txt", "url": "https://example.com/a?b&c"}
+{"name": "example.com/b", "versions": ">= v1.0.0", "licenceType": "MIT", "licenceFile": "docs/LICENCE"}
+{"name": "example.com/*@v1.2.3", "licenceSha256": "0783ba14ae06cc4ae3aa57f19d9c59a3346532a6e5200e61722db83bd3d74d0b"}
+`
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "team"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team", "overrides.json"), []byte(`{"extends": "../overrides.json"}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "overrides.json"), []byte(content), 0o600))
+
+	overrides, err := LoadOverrides(filepath.Join(dir, "team", "overrides.json"))
+	require.NoError(t, err)
+	require.Len(t, overrides, 3)
+
+	out := filepath.Join(dir, "pruned.json")
+	require.NoError(t, WriteOverrides(out, overrides))
+
+	written, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Contains(t, string(written), `{"name":"example.com/a","licenceTextOverrideFile":"licences/a.txt","url":"https://example.com/a?b&c"}`)
+
+	reloaded, err := LoadOverrides(out)
+	require.NoError(t, err)
+	for key, override := range reloaded {
+		for field, source := range override.OverrideSources {
+			require.Equal(t, out, source)
+			require.Contains(t, overrides[key].OverrideSources, field)
+		}
+		override.OverrideSources = overrides[key].OverrideSources
+		reloaded[key] = override
+	}
+	require.Equal(t, overrides, reloaded)
+
+	// licence text override files must be in the directory of the written file
+	require.ErrorContains(t, WriteOverrides(filepath.Join(dir, "team", "pruned.json"), overrides), "is not in the directory of")
+}
@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package dependency
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// versionOperators are the comparison operators supported in version ranges.
+// Longer operators must come first so that they are matched before their prefixes.
+var versionOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// VersionRange is a set of constraints that a module version must satisfy, such as ">= v1.2.0, < v2.0.0".
+// Constraints are separated by commas or whitespace and all of them must be satisfied. A version without an
+// operator must match exactly. An empty range or "*" matches any version.
+type VersionRange struct {
+	raw         string
+	constraints []versionConstraint
+}
+
+type versionConstraint struct {
+	op      string
+	version string
+}
+
+// ParseVersionRange parses the given version range.
+func ParseVersionRange(s string) (VersionRange, error) {
+	vr := VersionRange{raw: strings.TrimSpace(s)}
+	if vr.raw == "" || vr.raw == "*" {
+		return vr, nil
+	}
+
+	fields := strings.FieldsFunc(vr.raw, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	for i := 0; i < len(fields); i++ {
+		op, version := splitOperator(fields[i])
+		// allow whitespace between the operator and the version (e.g. ">= v1.2.0")
+		if version == "" && i+1 < len(fields) {
+			i++
+			version = fields[i]
+		}
+
+		if op == "" {
+			op = "="
+		}
+
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+
+		if !semver.IsValid(version) {
+			return VersionRange{}, fmt.Errorf("invalid version %q in version range %q", version, s)
+		}
+
+		vr.constraints = append(vr.constraints, versionConstraint{op: op, version: version})
+	}
+
+	return vr, nil
+}
+
+func splitOperator(s string) (string, string) {
+	for _, op := range versionOperators {
+		if strings.HasPrefix(s, op) {
+			return op, strings.TrimPrefix(s, op)
+		}
+	}
+
+	return "", s
+}
+
+// Contains returns true if the given version satisfies all constraints of the range.
+// Versions that are not valid semantic versions only satisfy a range without constraints.
+func (vr VersionRange) Contains(version string) bool {
+	if len(vr.constraints) == 0 {
+		return true
+	}
+
+	if !semver.IsValid(version) {
+		return false
+	}
+
+	for _, c := range vr.constraints {
+		cmp := semver.Compare(version, c.version)
+		var ok bool
+		switch c.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsExact returns true if the range matches a single version.
+func (vr VersionRange) IsExact() bool {
+	return len(vr.constraints) == 1 && vr.constraints[0].op == "="
+}
+
+// IsAny returns true if the range matches any version.
+func (vr VersionRange) IsAny() bool {
+	return len(vr.constraints) == 0
+}
+
+// String returns the range as it was written.
+func (vr VersionRange) String() string {
+	return vr.raw
+}
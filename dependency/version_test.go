@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package dependency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionRange(t *testing.T) {
+	testCases := []struct {
+		versionRange string
+		version      string
+		want         bool
+	}{
+		{versionRange: "", version: "v1.2.3", want: true},
+		{versionRange: "*", version: "not-a-version", want: true},
+		{versionRange: "v1.2.3", version: "v1.2.3", want: true},
+		{versionRange: "1.2.3", version: "v1.2.3", want: true},
+		{versionRange: "v1.2.3", version: "v1.2.4", want: false},
+		{versionRange: ">= v1.6.0", version: "v1.6.0", want: true},
+		{versionRange: ">=v1.6.0", version: "v1.5.7", want: false},
+		{versionRange: ">=v1.2.0 <v2.0.0", version: "v1.9.9", want: true},
+		{versionRange: ">=v1.2.0, <v2.0.0", version: "v2.0.0", want: false},
+		{versionRange: "< v1.0.0", version: "v0.0.0-20161205141322-d520615e531a", want: true},
+		{versionRange: "!=v1.0.0", version: "v1.0.0+incompatible", want: false},
+		{versionRange: ">v1.0.0", version: "not-a-version", want: false},
+		{versionRange: "<=v2", version: "v2.0.0", want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.versionRange+" "+tc.version, func(t *testing.T) {
+			vr, err := ParseVersionRange(tc.versionRange)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, vr.Contains(tc.version))
+		})
+	}
+}
+
+func TestParseVersionRangeInvalid(t *testing.T) {
+	for _, s := range []string{">=", ">= latest", "v1.2.3.4"} {
+		t.Run(s, func(t *testing.T) {
+			_, err := ParseVersionRange(s)
+			require.Error(t, err)
+		})
+	}
+}
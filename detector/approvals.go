@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/licence"
+)
+
+// approvalsFile represents the structure of the approvals file.
+type approvalsFile struct {
+	Approvals []dependency.Approval `json:"approvals"`
+}
+
+// Approvals holds the recorded reviews of maybelisted licences.
+type Approvals struct {
+	entries []approval
+}
+
+type approval struct {
+	dependency.Approval
+	versions dependency.VersionRange
+}
+
+// LoadApprovals loads approvals from the given path. No approvals are loaded if the path is empty.
+func LoadApprovals(path string) (*Approvals, error) {
+	approvals := &Approvals{}
+	if path == "" {
+		return approvals, nil
+	}
+
+	approvalBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approvals: %w", err)
+	}
+
+	var af approvalsFile
+	if err := json.Unmarshal(approvalBytes, &af); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal approvals: %w", err)
+	}
+
+	for i, a := range af.Approvals {
+		switch {
+		case a.Module == "":
+			return nil, fmt.Errorf("approval %d must specify the module", i)
+		case a.Licence == "":
+			return nil, fmt.Errorf("approval for %s must specify the licence", a.Module)
+		case a.Reviewer == "":
+			return nil, fmt.Errorf("approval for %s must specify the reviewer", a.Module)
+		}
+
+		if _, err := time.Parse(exceptionDateFormat, a.Date); err != nil {
+			return nil, fmt.Errorf("approval for %s must specify the review date in the format YYYY-MM-DD: %w", a.Module, err)
+		}
+
+		versions, err := dependency.ParseVersionRange(a.Versions)
+		if err != nil {
+			return nil, fmt.Errorf("approval for %s has an invalid version range: %w", a.Module, err)
+		}
+
+		if a.Licence, err = licence.NormaliseExpression(a.Licence); err != nil {
+			return nil, fmt.Errorf("approval for %s has an invalid licence: %w", a.Module, err)
+		}
+
+		approvals.entries = append(approvals.entries, approval{Approval: a, versions: versions})
+	}
+
+	return approvals, nil
+}
+
+// Find returns the approval that covers the module, version and licence of the given dependency.
+func (a *Approvals) Find(depInfo dependency.Info) *dependency.Approval {
+	if a == nil {
+		return nil
+	}
+
+	for _, e := range a.entries {
+		if e.Module == depInfo.Name && e.Licence == licence.Canonical(depInfo.LicenceType) && e.versions.Contains(depInfo.Version) {
+			found := e.Approval
+			return &found
+		}
+	}
+
+	return nil
+}
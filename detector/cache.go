@@ -0,0 +1,117 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package detector
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"go.elastic.co/go-licence-detector/cache"
+	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/licencedb"
+)
+
+// WithCache sets the cache of the licence files and licence types detected for module versions. The dbHash returned
+// by ClassifierHash for the licence database of the classifier is part of the cache keys, so that licences are
+// classified again when the database changes. Results are not cached by default.
+func WithCache(c *cache.Cache, dbHash string) Option {
+	return func(d *Detector) {
+		d.cache = c
+		d.dbHash = dbHash
+	}
+}
+
+// ClassifierHash returns the hash of the licence texts of a licence database loaded by LoadLicenceDB. It must be
+// given the database the classifier was created from with NewClassifierFromDB.
+func ClassifierHash(licenceDB []byte) (string, error) {
+	dbHash, err := licencedb.Hash(bytes.NewReader(licenceDB))
+	if err != nil {
+		return "", fmt.Errorf("failed to hash licence database: %w", err)
+	}
+
+	return dbHash, nil
+}
+
+// lookupCache returns the cache entry of the dependency. Entries found with another search depth or whose licence
+// file no longer has the recorded hash are ignored.
+func (d *Detector) lookupCache(depInfo dependency.Info) (cache.Entry, bool) {
+	entry, ok := d.cache.Get(depInfo.Name, depInfo.Version, d.dbHash)
+	if !ok || entry.SearchDepth != d.searchDepth {
+		return cache.Entry{}, false
+	}
+
+	if entry.LicenceFile == "" {
+		return entry, true
+	}
+
+	licenceFile, err := securejoin.SecureJoin(depInfo.Dir, filepath.FromSlash(entry.LicenceFile))
+	if err != nil {
+		return cache.Entry{}, false
+	}
+
+	if sum, err := hashFile(licenceFile); err != nil || sum != entry.LicenceSha256 {
+		return cache.Entry{}, false
+	}
+
+	return entry, true
+}
+
+// storeCache records the licence file found for the dependency and, if it was classified, its licence type. The
+// licence type of the previous entry is kept if the licence type was given by an override. Failures to write the
+// cache are ignored as they only cause the detection to be repeated.
+func (d *Detector) storeCache(depInfo dependency.Info, previous cache.Entry, classified bool) {
+	entry := cache.Entry{Module: depInfo.Name, Version: depInfo.Version, DBHash: d.dbHash, SearchDepth: d.searchDepth}
+	if depInfo.LicenceFile != "" {
+		relPath, err := filepath.Rel(depInfo.Dir, depInfo.LicenceFile)
+		if err != nil {
+			return
+		}
+
+		if entry.LicenceSha256, err = hashFile(depInfo.LicenceFile); err != nil {
+			return
+		}
+
+		entry.LicenceFile = filepath.ToSlash(relPath)
+	}
+
+	switch {
+	case classified:
+		entry.LicenceType, entry.Confidence = depInfo.LicenceType, depInfo.LicenceConfidence
+	case previous.LicenceFile == entry.LicenceFile && previous.LicenceSha256 == entry.LicenceSha256:
+		entry.LicenceType, entry.Confidence = previous.LicenceType, previous.Confidence
+	}
+
+	if entry != previous {
+		_ = d.cache.Put(entry)
+	}
+}
+
+func hashFile(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,224 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package detector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"go.elastic.co/go-licence-detector/dependency"
+	"golang.org/x/mod/modfile"
+)
+
+// pkg holds the package information from go list -deps -test -json that is required to classify dependencies.
+type pkg struct {
+	ImportPath string   // import path of the package. Test variants have a suffix such as " [example.com/a.test]".
+	ForTest    string   // package is only for use in the named test
+	Module     *module  // module containing the package. Nil for standard library packages.
+	Deps       []string // all (recursively) imported dependencies
+}
+
+// packageGraph identifies the modules reachable from the packages of the main module and from its tools.
+type packageGraph struct {
+	shipped      map[string]struct{} // modules reachable without tests
+	tested       map[string]struct{} // modules reachable with tests
+	tools        map[string]struct{} // modules reachable from the tool packages
+	missingTools []string            // tool packages that are not in the package list
+}
+
+// parsePackages reads the output of go list -deps -test -json. Packages of the main module that are not test
+// variants are the roots of the shipped code, so that any module reachable from them is shipped. The given tool
+// packages are the roots of the tools, which are only in the package list if it was produced with the tool pattern.
+func parsePackages(data io.Reader, toolPaths []string) (*packageGraph, error) {
+	pkgs := make(map[string]*pkg)
+	var roots []*pkg
+
+	decoder := json.NewDecoder(data)
+	for {
+		var p pkg
+		if err := decoder.Decode(&p); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse packages: %w", err)
+		}
+
+		pkgs[p.ImportPath] = &p
+		if p.Module != nil && p.Module.Main && !isTestPackage(&p) {
+			roots = append(roots, &p)
+		}
+	}
+
+	graph := &packageGraph{
+		shipped: make(map[string]struct{}),
+		tested:  make(map[string]struct{}),
+		tools:   make(map[string]struct{}),
+	}
+
+	for _, p := range pkgs {
+		if p.Module != nil {
+			graph.tested[p.Module.Path] = struct{}{}
+		}
+	}
+
+	for _, root := range roots {
+		for _, dep := range root.Deps {
+			if p, ok := pkgs[dep]; ok && p.Module != nil {
+				graph.shipped[p.Module.Path] = struct{}{}
+			}
+		}
+	}
+
+	for _, toolPath := range toolPaths {
+		tool, ok := pkgs[toolPath]
+		if !ok {
+			graph.missingTools = append(graph.missingTools, toolPath)
+			continue
+		}
+
+		for _, dep := range append([]string{toolPath}, tool.Deps...) {
+			if p, ok := pkgs[dep]; ok && p.Module != nil && !p.Module.Main {
+				graph.tools[p.Module.Path] = struct{}{}
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+func isTestPackage(p *pkg) bool {
+	return p.ForTest != "" || strings.Contains(p.ImportPath, " [") || strings.HasSuffix(p.ImportPath, ".test")
+}
+
+// readToolPaths returns the package paths of the tool directives in the given go.mod file.
+// A missing go.mod file has no tools.
+func readToolPaths(goModPath string) ([]string, error) {
+	if goModPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", goModPath, err)
+	}
+
+	f, err := modfile.Parse(goModPath, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", goModPath, err)
+	}
+
+	toolPaths := make([]string, len(f.Tool))
+	for i, t := range f.Tool {
+		toolPaths[i] = t.Path
+	}
+
+	return toolPaths, nil
+}
+
+// toolModules returns the paths of the modules providing the given tool packages.
+// Each package belongs to the module with the longest path that is a prefix of the package path.
+func toolModules(toolPaths, modulePaths []string) map[string]struct{} {
+	tools := make(map[string]struct{}, len(toolPaths))
+	for _, toolPath := range toolPaths {
+		var owner string
+		for _, modPath := range modulePaths {
+			if (toolPath == modPath || strings.HasPrefix(toolPath, modPath+"/")) && len(modPath) > len(owner) {
+				owner = modPath
+			}
+		}
+
+		if owner != "" {
+			tools[owner] = struct{}{}
+		}
+	}
+
+	return tools
+}
+
+// classifyContexts determines the context of each dependency. Dependencies reachable from the packages of the main
+// module keep their direct or indirect context. Dependencies reachable from the tool packages are tool dependencies
+// if the package list includes the tools. Otherwise, only the modules providing the tools are classified as tool
+// dependencies and a warning is returned. Without package information, only indirect dependencies providing tools are
+// classified as tool dependencies because direct dependencies may be linked into the main module as well.
+func classifyContexts(deps *dependencies, packages io.Reader) ([]string, error) {
+	var goModPath string
+	if deps.main != nil {
+		goModPath = deps.main.GoMod
+	}
+
+	toolPaths, err := readToolPaths(goModPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool directives: %w", err)
+	}
+
+	var graph *packageGraph
+	if packages != nil {
+		if graph, err = parsePackages(packages, toolPaths); err != nil {
+			return nil, err
+		}
+	}
+
+	var warnings []string
+	switch {
+	case len(toolPaths) > 0 && graph == nil:
+		warnings = append(warnings, fmt.Sprintf("no package list is given, so only the indirect modules providing the tools %s are classified as tool dependencies", strings.Join(toolPaths, ", ")))
+	case graph != nil && len(graph.missingTools) > 0:
+		warnings = append(warnings, fmt.Sprintf("the package list does not include the tools %s, so only the modules providing them are classified as tool dependencies. Add the tool pattern to go list -deps -test -json to classify their dependencies.", strings.Join(graph.missingTools, ", ")))
+	}
+
+	tools := toolModules(toolPaths, deps.modulePaths)
+
+	for _, mod := range append(append([]*module{}, deps.direct...), deps.indirect...) {
+		mod.context = dependency.ContextDirect
+		if mod.Indirect {
+			mod.context = dependency.ContextIndirect
+		}
+
+		_, isTool := tools[mod.Path]
+
+		if graph == nil {
+			if isTool && mod.Indirect {
+				mod.context = dependency.ContextTool
+			}
+			continue
+		}
+
+		if _, isShipped := graph.shipped[mod.Path]; isShipped {
+			continue
+		}
+
+		if _, isToolDep := graph.tools[mod.Path]; isTool || isToolDep {
+			mod.context = dependency.ContextTool
+			continue
+		}
+
+		if _, isTested := graph.tested[mod.Path]; isTested {
+			mod.context = dependency.ContextTest
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package detector
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/go-licence-detector/dependency"
+)
+
+func TestClassifyContexts(t *testing.T) {
+	goMod := filepath.Join(t.TempDir(), "go.mod")
+	require.NoError(t, os.WriteFile(goMod, []byte(`module github.com/charith-elastic/licence-detector
+
+go 1.24
+
+tool (
+	github.com/ekzhu/minhash-lsh/cmd/lsh
+	github.com/gorhill/cronexpr/cmd/cronexpr
+)
+`), 0o600))
+
+	mkDeps := func() *dependencies {
+		return &dependencies{
+			main: &module{Path: "github.com/charith-elastic/licence-detector", Main: true, GoMod: goMod},
+			direct: []*module{
+				{Path: "github.com/ekzhu/minhash-lsh"},
+				{Path: "github.com/elastic/test"},
+			},
+			indirect: []*module{
+				{Path: "github.com/dgryski/go-minhash", Indirect: true},
+				{Path: "github.com/gorhill/cronexpr", Indirect: true},
+				{Path: "github.com/gorhill/cronexpr/cmd", Indirect: true},
+				{Path: "gopkg.in/russross/blackfriday.v2", Indirect: true},
+			},
+			modulePaths: []string{
+				"github.com/dgryski/go-minhash",
+				"github.com/ekzhu/minhash-lsh",
+				"github.com/elastic/test",
+				"github.com/gorhill/cronexpr",
+				"github.com/gorhill/cronexpr/cmd",
+				"gopkg.in/russross/blackfriday.v2",
+			},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		packages     string
+		want         map[string]string
+		wantWarnings []string
+	}{
+		{
+			name: "WithoutPackages",
+			want: map[string]string{
+				"github.com/dgryski/go-minhash":    dependency.ContextIndirect,
+				"github.com/ekzhu/minhash-lsh":     dependency.ContextDirect,
+				"github.com/elastic/test":          dependency.ContextDirect,
+				"github.com/gorhill/cronexpr":      dependency.ContextIndirect,
+				"github.com/gorhill/cronexpr/cmd":  dependency.ContextTool,
+				"gopkg.in/russross/blackfriday.v2": dependency.ContextIndirect,
+			},
+			wantWarnings: []string{"no package list is given, so only the indirect modules providing the tools github.com/ekzhu/minhash-lsh/cmd/lsh, github.com/gorhill/cronexpr/cmd/cronexpr are classified as tool dependencies"},
+		},
+		{
+			name:     "WithPackages",
+			packages: "testdata/packages.json",
+			want: map[string]string{
+				"github.com/dgryski/go-minhash":    dependency.ContextIndirect,
+				"github.com/ekzhu/minhash-lsh":     dependency.ContextDirect,
+				"github.com/elastic/test":          dependency.ContextDirect,
+				"github.com/gorhill/cronexpr":      dependency.ContextTest,
+				"github.com/gorhill/cronexpr/cmd":  dependency.ContextTool,
+				"gopkg.in/russross/blackfriday.v2": dependency.ContextIndirect,
+			},
+			wantWarnings: []string{"the package list does not include the tools github.com/ekzhu/minhash-lsh/cmd/lsh, github.com/gorhill/cronexpr/cmd/cronexpr, so only the modules providing them are classified as tool dependencies. Add the tool pattern to go list -deps -test -json to classify their dependencies."},
+		},
+		{
+			name:     "WithToolPackages",
+			packages: "testdata/packages-tools.json",
+			want: map[string]string{
+				// only imported by the cronexpr tool
+				"github.com/dgryski/go-minhash":    dependency.ContextTool,
+				"github.com/ekzhu/minhash-lsh":     dependency.ContextDirect,
+				"github.com/elastic/test":          dependency.ContextDirect,
+				"github.com/gorhill/cronexpr":      dependency.ContextTest,
+				"github.com/gorhill/cronexpr/cmd":  dependency.ContextTool,
+				"gopkg.in/russross/blackfriday.v2": dependency.ContextIndirect,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var packages io.Reader
+			if tc.packages != "" {
+				f, err := os.Open(tc.packages)
+				require.NoError(t, err)
+				defer f.Close()
+				packages = f
+			}
+
+			deps := mkDeps()
+			warnings, err := classifyContexts(deps, packages)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantWarnings, warnings)
+
+			got := make(map[string]string)
+			for _, mod := range append(deps.direct, deps.indirect...) {
+				got[mod.Path] = mod.context
+			}
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -18,34 +18,67 @@
 package detector // import "go.elastic.co/go-licence-detector/detector"
 
 import (
+	"context"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 	"time"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/google/licenseclassifier"
+	"github.com/google/licenseclassifier/stringclassifier"
+	"github.com/pmezard/go-difflib/difflib"
 	"go.elastic.co/go-licence-detector/assets"
+	"go.elastic.co/go-licence-detector/cache"
 	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/licence"
+	"go.elastic.co/go-licence-detector/licencedb"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
 	// detectionThreshold is the minimum confidence score required from the licence classifier.
 	detectionThreshold = 0.85
+	// lowConfidenceThreshold is the minimum confidence score of the nearest licence for a text that is not detected
+	// to be reported as a licence detected with low confidence rather than an unknown licence.
+	lowConfidenceThreshold = 0.5
+	// DefaultSearchDepth is the default maximum depth of the directories searched for the licence file of a module.
+	DefaultSearchDepth = 3
 )
 
 var errLicenceNotFound = errors.New("failed to detect licence")
 
+// skippedDirs are the names of the directories that are not searched for licence files, as they hold test fixtures,
+// vendored copies of other modules or tooling files rather than the licence of the module.
+var skippedDirs = map[string]struct{}{
+	".git":         {},
+	"node_modules": {},
+	"testdata":     {},
+	"vendor":       {},
+}
+
+// readmeRegex matches the README files that are classified when a module has no licence file, as some modules only
+// include their licence text in the README.
+var readmeRegex = regexp.MustCompile(`^(?i:readme(\.(txt|md|markdown|rst))?)$`)
+
 type dependencies struct {
-	direct   []*module
-	indirect []*module
+	main        *module
+	direct      []*module
+	indirect    []*module
+	modulePaths []string  // paths of all modules required by the main module, including the excluded indirect ones
+	required    []*module // all modules required by the main module, including the modules that are not downloaded
 }
 
 type module struct {
@@ -56,12 +89,42 @@ type module struct {
 	Indirect bool       // is this module only an indirect dependency of main module?
 	Dir      string     // directory holding files for this module, if any
 	Replace  *module    // replace directive
+	GoMod    string     // path to go.mod file describing module, if any
+
+	context string // context in which the module is used, determined by classifyContexts
 }
 
 // NewClassifier creates a new instance of the licence classifier.
 func NewClassifier(dataPath string) (*licenseclassifier.License, error) {
+	licenceDB, err := LoadLicenceDB(dataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClassifierFromDB(licenceDB)
+}
+
+// NewClassifierFromDB creates a new instance of the licence classifier from a licence database loaded by
+// LoadLicenceDB.
+func NewClassifierFromDB(licenceDB []byte) (*licenseclassifier.License, error) {
+	return licenseclassifier.New(detectionThreshold, licenseclassifier.ArchiveBytes(licenceDB))
+}
+
+// LoadLicenceDB reads the licence database from the given path. Embedded database, including the additional
+// licences shipped with the detector, is loaded if the path is empty.
+func LoadLicenceDB(dataPath string) ([]byte, error) {
 	if dataPath == "" {
-		return licenseclassifier.New(detectionThreshold, licenseclassifier.ArchiveBytes(assets.LicenceDB))
+		licences, err := fs.Sub(assets.Licences, "licences")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedded licences: %w", err)
+		}
+
+		licenceDB, err := licencedb.Append(assets.LicenceDB, licences)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add embedded licences to licence database: %w", err)
+		}
+
+		return licenceDB, nil
 	}
 
 	absPath, err := filepath.Abs(dataPath)
@@ -69,19 +132,226 @@ func NewClassifier(dataPath string) (*licenseclassifier.License, error) {
 		return nil, fmt.Errorf("failed to determine absolute path of licence data file: %w", err)
 	}
 
-	return licenseclassifier.New(detectionThreshold, licenseclassifier.Archive(absPath))
+	licenceDB, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read licence data file: %w", err)
+	}
+
+	return licenceDB, nil
 }
 
-// Detect searches the dependencies on disk and detects licences.
-func Detect(data io.Reader, classifier *licenseclassifier.License, rules *Rules, overrides dependency.Overrides, includeIndirect bool) (*dependency.List, error) {
+// Classifier classifies licence texts. It is implemented by the licence classifier returned by NewClassifier.
+type Classifier interface {
+	// MultipleMatch returns the licences matching the text with enough confidence, from the most to the least
+	// confident match.
+	MultipleMatch(contents string, includeHeaders bool) stringclassifier.Matches
+	// NearestMatch returns the licence closest to the text, regardless of the confidence.
+	NearestMatch(contents string) *stringclassifier.Match
+}
+
+// Detector detects the licences of the dependencies of a module. It can be reused to detect the licences of several
+// dependency lists.
+type Detector struct {
+	classifier      Classifier
+	rules           *Rules
+	overrides       dependency.Overrides
+	includeIndirect bool
+	workers         int
+	searchDepth     int
+	cache           *cache.Cache
+	dbHash          string // hash of the licence database of the classifier, part of the cache keys
+}
+
+// Option configures a Detector.
+type Option func(*Detector)
+
+// WithClassifier sets the licence classifier. The classifier created by NewClassifier with the embedded licence
+// database is used by default.
+func WithClassifier(classifier Classifier) Option {
+	return func(d *Detector) {
+		d.classifier = classifier
+	}
+}
+
+// WithRules sets the licence rules. The embedded rules are used by default.
+func WithRules(rules *Rules) Option {
+	return func(d *Detector) {
+		d.rules = rules
+	}
+}
+
+// WithOverrides sets the overrides of the detected dependency information.
+func WithOverrides(overrides dependency.Overrides) Option {
+	return func(d *Detector) {
+		d.overrides = overrides
+	}
+}
+
+// WithIndirect sets whether the licences of indirect dependencies are detected. Only direct dependencies are
+// detected by default.
+func WithIndirect(includeIndirect bool) Option {
+	return func(d *Detector) {
+		d.includeIndirect = includeIndirect
+	}
+}
+
+// WithWorkers sets the number of modules whose licence is detected concurrently. The number of CPUs is used by
+// default or if n is not positive.
+func WithWorkers(n int) Option {
+	return func(d *Detector) {
+		d.workers = n
+	}
+}
+
+// WithSearchDepth sets the maximum depth of the directories searched for the licence file of a module, the module
+// root being at depth 1. DefaultSearchDepth is used by default or if n is not positive.
+func WithSearchDepth(n int) Option {
+	return func(d *Detector) {
+		d.searchDepth = n
+	}
+}
+
+// New creates a Detector configured by the given options.
+func New(opts ...Option) (*Detector, error) {
+	d := &Detector{}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if d.classifier == nil {
+		classifier, err := NewClassifier("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create licence classifier: %w", err)
+		}
+		d.classifier = classifier
+	}
+
+	if d.rules == nil {
+		rules, err := LoadRules("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rules: %w", err)
+		}
+		d.rules = rules
+	}
+
+	if d.overrides == nil {
+		d.overrides = make(dependency.Overrides)
+	}
+
+	if d.workers <= 0 {
+		d.workers = runtime.NumCPU()
+	}
+
+	if d.searchDepth <= 0 {
+		d.searchDepth = DefaultSearchDepth
+	}
+
+	return d, nil
+}
+
+// Source holds the dependency information read by Detector.Detect.
+type Source struct {
+	// Modules is the output of go list -m -json all.
+	Modules io.Reader
+	// Packages is the optional output of go list -deps -test -json ./... tool, which is used to identify the
+	// dependencies that are only used by tests or tools.
+	Packages io.Reader
+}
+
+// Detect searches the dependencies on disk and detects licences. All dependencies are processed before a
+// *DetectionError listing the dependencies whose licence is not accepted is returned. Detection stops with the error
+// of the context if it is cancelled.
+func (d *Detector) Detect(ctx context.Context, source Source) (*dependency.List, error) {
 	// parse the output of go mod list
-	deps, err := parseDependencies(data, includeIndirect)
+	deps, err := parseDependencies(source.Modules, d.includeIndirect)
+	if err != nil {
+		return nil, err
+	}
+
+	// determine how each dependency is used
+	warnings, err := classifyContexts(deps, source.Packages)
 	if err != nil {
 		return nil, err
 	}
 
+	// determine the licence under which the main module is distributed
+	outbound, err := determineOutboundLicence(d.classifier, d.rules, deps.main)
+	if err != nil {
+		// the outbound licence is only required when it is set by the rules, so an unrecognised licence of the main
+		// module only disables the compatibility checks
+		if classificationReason(err) == "" {
+			return nil, err
+		}
+		warnings = append(warnings, fmt.Sprintf("%v. Licence compatibility is not checked, set the outbound licence to check it.", err))
+	} else if outbound != "" && !licence.HasCompatibility(outbound) {
+		warnings = append(warnings, fmt.Sprintf("no compatibility information for outbound licence %s of main module %s. Licence compatibility is not checked.", outbound, deps.main.Path))
+	}
+
 	// find licences for each dependency
-	return detectLicences(classifier, rules, deps, overrides)
+	depList, err := d.detectLicences(ctx, deps, outbound)
+	if depList != nil {
+		depList.Warnings = warnings
+	}
+
+	return depList, err
+}
+
+// Detect searches the dependencies on disk and detects licences.
+// It is a shorthand for creating a Detector with New and calling its Detect method. Package information, used to
+// identify the dependencies that are only used by tests, can only be given to Detector.Detect.
+func Detect(data io.Reader, classifier *licenseclassifier.License, rules *Rules, overrides dependency.Overrides, includeIndirect bool) (*dependency.List, error) {
+	return DetectWithPackages(data, nil, classifier, rules, overrides, includeIndirect)
+}
+
+// DetectWithPackages searches the dependencies on disk and detects licences like Detect.
+// The optional packages reader provides the output of go list -deps -test -json, which is used to identify the
+// dependencies that are only used by tests.
+//
+// Deprecated: use New and Detector.Detect.
+func DetectWithPackages(data, packages io.Reader, classifier *licenseclassifier.License, rules *Rules, overrides dependency.Overrides, includeIndirect bool) (*dependency.List, error) {
+	d, err := New(WithClassifier(classifier), WithRules(rules), WithOverrides(overrides), WithIndirect(includeIndirect))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Detect(context.Background(), Source{Modules: data, Packages: packages})
+}
+
+// determineOutboundLicence returns the outbound licence from the rules or detects it from the licence file in the
+// root directory of the main module. It returns an empty string if the main module does not have a licence file, and
+// a classification error if its licence is not recognised.
+func determineOutboundLicence(classifier Classifier, rules *Rules, main *module) (string, error) {
+	if rules.OutboundLicence != "" {
+		return rules.OutboundLicence, nil
+	}
+
+	if main == nil || main.Dir == "" {
+		return "", nil
+	}
+
+	entries, err := os.ReadDir(main.Dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read directory of main module %s: %w", main.Path, err)
+	}
+
+	licenceRegex := buildLicenceRegex()
+	for _, entry := range entries {
+		if entry.IsDir() || !licenceRegex.MatchString(entry.Name()) {
+			continue
+		}
+
+		outbound, _, err := detectLicenceType(classifier, filepath.Join(main.Dir, entry.Name()))
+		if err != nil {
+			return "", fmt.Errorf("failed to detect outbound licence of main module %s: %w", main.Path, err)
+		}
+
+		return outbound, nil
+	}
+
+	return "", nil
 }
 
 func parseDependencies(data io.Reader, includeIndirect bool) (*dependencies, error) {
@@ -96,7 +366,15 @@ func parseDependencies(data io.Reader, includeIndirect bool) (*dependencies, err
 			return deps, fmt.Errorf("failed to parse dependencies: %w", err)
 		}
 
-		if !mod.Main && mod.Dir != "" {
+		if mod.Main {
+			deps.main = &mod
+			continue
+		}
+
+		deps.modulePaths = append(deps.modulePaths, mod.Path)
+		deps.required = append(deps.required, &mod)
+
+		if mod.Dir != "" {
 			if mod.Indirect {
 				if includeIndirect {
 					deps.indirect = append(deps.indirect, &mod)
@@ -108,72 +386,275 @@ func parseDependencies(data io.Reader, includeIndirect bool) (*dependencies, err
 	}
 }
 
-func detectLicences(classifier *licenseclassifier.License, rules *Rules, deps *dependencies, overrides dependency.Overrides) (*dependency.List, error) {
-	depList := &dependency.List{}
+func (d *Detector) detectLicences(ctx context.Context, deps *dependencies, outbound string) (*dependency.List, error) {
+	depList := &dependency.List{OutboundLicence: outbound}
 	licenceRegex := buildLicenceRegex()
 
-	var err error
-	if depList.Direct, err = doDetectLicences(licenceRegex, classifier, rules, deps.direct, overrides); err != nil {
+	var (
+		directFailures, indirectFailures []*ModuleError
+		err                              error
+	)
+	if depList.Direct, directFailures, err = d.doDetectLicences(ctx, licenceRegex, deps.direct, outbound); err != nil {
 		return depList, err
 	}
 
-	if depList.Indirect, err = doDetectLicences(licenceRegex, classifier, rules, deps.indirect, overrides); err != nil {
+	if depList.Indirect, indirectFailures, err = d.doDetectLicences(ctx, licenceRegex, deps.indirect, outbound); err != nil {
 		return depList, err
 	}
 
+	isExcluded := func(depInfo dependency.Info) bool {
+		if depInfo.Exclude {
+			depList.Excluded = append(depList.Excluded, dependency.Excluded{
+				Module:   depInfo.Name,
+				Version:  depInfo.Version,
+				Override: depInfo.Override,
+				Reason:   depInfo.Reason,
+			})
+		}
+		return depInfo.Exclude
+	}
+	depList.Direct = slices.DeleteFunc(depList.Direct, isExcluded)
+	depList.Indirect = slices.DeleteFunc(depList.Indirect, isExcluded)
+
+	for _, depInfoList := range [][]dependency.Info{depList.Direct, depList.Indirect} {
+		for _, depInfo := range depInfoList {
+			if depInfo.Exception != nil {
+				depList.Exceptions = append(depList.Exceptions, *depInfo.Exception)
+			}
+
+			if depInfo.ReviewRequired {
+				depList.PendingReviews = append(depList.PendingReviews, dependency.PendingReview{
+					Module:  depInfo.Name,
+					Version: depInfo.Version,
+					Licence: depInfo.LicenceType,
+				})
+			}
+		}
+	}
+
+	if failures := slices.Concat(directFailures, indirectFailures); len(failures) > 0 {
+		return depList, &DetectionError{Modules: failures}
+	}
+
 	return depList, nil
 }
 
-func doDetectLicences(licenceRegex *regexp.Regexp, classifier *licenseclassifier.License, rules *Rules, depList []*module, overrides dependency.Overrides) ([]dependency.Info, error) {
+// doDetectLicences detects the licences of the modules with the configured number of workers. The dependency
+// information and the failures are in the order of the modules.
+func (d *Detector) doDetectLicences(ctx context.Context, licenceRegex *regexp.Regexp, depList []*module, outbound string) ([]dependency.Info, []*ModuleError, error) {
 	if len(depList) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
-	depInfoList := make([]dependency.Info, len(depList))
-	for i, mod := range depList {
-		depInfo := mkDepInfo(mod, overrides)
+	// results are stored by module index so that the order does not depend on the order in which the workers finish
+	depInfos := make([]dependency.Info, len(depList))
+	modErrs := make([]*ModuleError, len(depList))
+
+	indexChan := make(chan int, min(d.workers, len(depList)))
+	group, ctx := errgroup.WithContext(ctx)
+
+	// start workers
+	for range min(d.workers, len(depList)) {
+		group.Go(func() error {
+			for i := range indexChan {
+				depInfo, err := d.detectModuleLicence(licenceRegex, depList[i], outbound)
+				if err != nil {
+					// all modules are processed so that the failures are reported together
+					if !errors.As(err, &modErrs[i]) {
+						return err
+					}
+					continue
+				}
+				depInfos[i] = depInfo
+			}
 
-		// find the licence file if the override hasn't provided one
-		if depInfo.LicenceFile == "" {
-			var err error
-			depInfo.LicenceFile, err = findLicenceFile(depInfo.Dir, licenceRegex)
-			if err != nil && !errors.Is(err, errLicenceNotFound) {
-				return nil, fmt.Errorf("failed to find licence file for %s in %s: %w", depInfo.Name, depInfo.Dir, err)
+			return nil
+		})
+	}
+
+	// distribute work to workers
+	group.Go(func() error {
+		defer close(indexChan)
+		for i := range depList {
+			if err := ctx.Err(); err != nil {
+				return err
 			}
-		} else if depInfo.LicenceTextOverrideFile == "" {
-			// if licence file is given but no overrides, use the selected licence file
-			licFile, err := securejoin.SecureJoin(depInfo.Dir, depInfo.LicenceFile)
-			if err != nil {
-				return nil, fmt.Errorf("failed to generate secure path to licence file of %s: %w", depInfo.Name, err)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case indexChan <- i:
 			}
-			depInfo.LicenceFile = licFile
 		}
+		return nil
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	depInfoList := make([]dependency.Info, 0, len(depList))
+	var failures []*ModuleError
+	for i := range depList {
+		if modErrs[i] != nil {
+			failures = append(failures, modErrs[i])
+			continue
+		}
+		depInfoList = append(depInfoList, depInfos[i])
+	}
 
-		// detect the licence type if the override hasn't provided one
-		if depInfo.LicenceType == "" {
-			if depInfo.LicenceFile == "" {
-				return nil, fmt.Errorf("no licence file found for %s. Add an override entry with licence type to continue.", depInfo.Name)
-			}
+	return depInfoList, failures, nil
+}
+
+// detectModuleLicence detects and checks the licence of the module. Problems with the licence of the module are
+// returned as a *ModuleError.
+func (d *Detector) detectModuleLicence(licenceRegex *regexp.Regexp, mod *module, outbound string) (dependency.Info, error) {
+	depInfo := mkDepInfo(mod, d.overrides)
+
+	// excluded dependencies are not checked as they are removed from the list by detectLicences
+	if depInfo.Exclude {
+		return depInfo, nil
+	}
 
+	fail := func(reason string, err error) (dependency.Info, error) {
+		return depInfo, &ModuleError{Module: depInfo.Name, Version: depInfo.Version, Reason: reason, Err: err}
+	}
+	invalidOverride := func(err error) error {
+		return &InvalidOverrideError{Module: depInfo.Name, Version: depInfo.Version, Override: depInfo.Override, Err: err}
+	}
+	unknownLicence := func(err error) error {
+		return &UnknownLicenceError{Module: depInfo.Name, Version: depInfo.Version, LicenceFile: depInfo.LicenceFile, Err: err}
+	}
+	disallowed := func(err error) error {
+		return &DisallowedLicenceError{Module: depInfo.Name, Version: depInfo.Version, Licence: depInfo.LicenceType, Err: err}
+	}
+
+	switch {
+	case depInfo.LicenceType != "":
+		depInfo.LicenceSource = dependency.LicenceSourceOverride
+	case depInfo.LicenceFile != "" || depInfo.LicenceText != "":
+		depInfo.LicenceSource = dependency.LicenceSourceOverrideFile
+	default:
+		depInfo.LicenceSource = dependency.LicenceSourceFile
+	}
+
+	// results of the search in the module directory are cached, unless the directory is a local replacement
+	cacheable := d.cache != nil && depInfo.LicenceFile == "" && depInfo.LicenceText == "" && depInfo.Version != "" && !depInfo.LocalReplacement
+
+	var (
+		entry              cache.Entry
+		cached, classified bool
+	)
+
+	// find the licence file if the override hasn't provided one or the licence text
+	if depInfo.LicenceFile == "" && depInfo.LicenceText == "" {
+		if cacheable {
+			entry, cached = d.lookupCache(depInfo)
+		}
+
+		if cached {
+			if entry.LicenceFile != "" {
+				depInfo.LicenceFile = filepath.Join(depInfo.Dir, filepath.FromSlash(entry.LicenceFile))
+			}
+		} else {
 			var err error
-			depInfo.LicenceType, err = detectLicenceType(classifier, depInfo.LicenceFile)
+			depInfo.LicenceFile, err = findLicenceFile(depInfo.Dir, licenceRegex, d.searchDepth)
+			if err != nil && !errors.Is(err, errLicenceNotFound) {
+				return depInfo, fmt.Errorf("failed to find licence file for %s in %s: %w", depInfo.Name, depInfo.Dir, err)
+			}
+		}
+	} else if depInfo.LicenceFile != "" && depInfo.LicenceTextOverrideFile == "" {
+		// if licence file is given but no overrides, use the selected licence file
+		licFile, err := securejoin.SecureJoin(depInfo.Dir, depInfo.LicenceFile)
+		if err != nil {
+			return fail(ReasonOverrideInvalid, invalidOverride(fmt.Errorf("failed to generate secure path to licence file of %s: %w", depInfo.Name, err)))
+		}
+		depInfo.LicenceFile = licFile
+	}
+
+	// fall back to the README of the module, which may hold the licence text, if there is no licence file and the
+	// override hasn't provided the licence type
+	if depInfo.LicenceFile == "" && depInfo.LicenceText == "" && depInfo.LicenceType == "" {
+		var err error
+		depInfo.LicenceFile, err = findReadme(depInfo.Dir)
+		if err != nil && !errors.Is(err, errLicenceNotFound) {
+			return depInfo, fmt.Errorf("failed to find README file for %s in %s: %w", depInfo.Name, depInfo.Dir, err)
+		}
+	}
+
+	if depInfo.LicenceSource == dependency.LicenceSourceFile && readmeRegex.MatchString(filepath.Base(depInfo.LicenceFile)) {
+		depInfo.LicenceSource = dependency.LicenceSourceReadme
+	}
+
+	if depInfo.LicenceSha256 != "" {
+		if err := checkLicenceHash(licenceRegex, d.searchDepth, depInfo); err != nil {
+			return fail(ReasonOverrideInvalid, invalidOverride(err))
+		}
+	}
+
+	// detect the licence type if the override hasn't provided one
+	if depInfo.LicenceType == "" {
+		var err error
+		switch {
+		case depInfo.LicenceText != "":
+			depInfo.LicenceType, depInfo.LicenceConfidence, err = classifyLicenceText(d.classifier, "the licence text of the override", depInfo.LicenceText)
 			if err != nil {
-				return nil, fmt.Errorf("failed to detect licence type of %s from %s: %w", depInfo.Name, depInfo.LicenceFile, err)
+				return fail(classificationReason(err), unknownLicence(fmt.Errorf("failed to detect licence type of %s: %w", depInfo.Name, err)))
 			}
+		case cached && entry.LicenceType != "":
+			depInfo.LicenceType, depInfo.LicenceConfidence = entry.LicenceType, entry.Confidence
+		case depInfo.LicenceFile != "":
+			classified = true
+			depInfo.LicenceType, depInfo.LicenceConfidence, err = detectLicenceType(d.classifier, depInfo.LicenceFile)
+			if err != nil {
+				// a README without a licence text does not make up for the missing licence file
+				if depInfo.LicenceSource == dependency.LicenceSourceReadme && classificationReason(err) != "" {
+					return fail(ReasonNoFile, &LicenceNotFoundError{Module: depInfo.Name, Version: depInfo.Version})
+				}
 
-			if depInfo.LicenceType == "" {
-				return nil, fmt.Errorf("licence unknown for %s. Add an override entry with licence type to continue.", depInfo.Name)
+				err = fmt.Errorf("failed to detect licence type of %s from %s: %w", depInfo.Name, depInfo.LicenceFile, err)
+				if reason := classificationReason(err); reason != "" {
+					return fail(reason, unknownLicence(err))
+				}
+				// the licence file found in the module exists, so only the licence file of an override can be missing
+				if depInfo.LicenceSource == dependency.LicenceSourceOverrideFile {
+					return fail(ReasonOverrideInvalid, invalidOverride(err))
+				}
+				return depInfo, err
 			}
+		default:
+			return fail(ReasonNoFile, &LicenceNotFoundError{Module: depInfo.Name, Version: depInfo.Version})
 		}
 
-		if !rules.IsAllowed(depInfo.LicenceType) {
-			return nil, fmt.Errorf("dependency %s uses licence %s which is not allowed by the rules file", depInfo.Name, depInfo.LicenceType)
+		if depInfo.LicenceType == "" {
+			return fail(ReasonUnknown, unknownLicence(fmt.Errorf("licence unknown for %s. Add an override entry with licence type to continue.", depInfo.Name)))
 		}
+	}
+
+	if cacheable {
+		d.storeCache(depInfo, entry, classified)
+	}
+
+	if depInfo.LicenceFile == "" && depInfo.LicenceText == "" && d.rules.RequiresLicenceFile(depInfo.LicenceType) {
+		return fail(ReasonNoFile, &LicenceNotFoundError{Module: depInfo.Name, Version: depInfo.Version, Licence: depInfo.LicenceType})
+	}
+
+	depInfo.LicenceCategory = string(licence.CategoryOfExpression(depInfo.LicenceType))
+
+	if err := d.rules.Check(&depInfo); err != nil {
+		return fail(ReasonDisallowed, disallowed(err))
+	}
 
-		depInfoList[i] = depInfo
+	if err := d.rules.CheckCompatibility(outbound, &depInfo); err != nil {
+		return fail(ReasonDisallowed, disallowed(err))
+	}
+
+	var err error
+	if depInfo.PolicyResults, err = d.rules.EvaluatePolicies(&depInfo); err != nil {
+		return depInfo, err
 	}
 
-	return depInfoList, nil
+	return depInfo, nil
 }
 
 func mkDepInfo(mod *module, overrides dependency.Overrides) dependency.Info {
@@ -196,9 +677,11 @@ func mkDepInfo(mod *module, overrides dependency.Overrides) dependency.Info {
 		}
 	}
 
-	override, ok := overrides[modName]
-	if !ok {
-		override = dependency.Info{}
+	override, _ := overrides.Find(modName, version)
+
+	var warnings []string
+	if mismatched := overrides.Mismatched(modName, version); len(mismatched) > 0 {
+		warnings = append(warnings, fmt.Sprintf("override %s was written for a different version than the resolved version %s", strings.Join(mismatched, ", "), version))
 	}
 
 	versionTime := "unknown"
@@ -213,9 +696,22 @@ func mkDepInfo(mod *module, overrides dependency.Overrides) dependency.Info {
 		VersionTime:             coalesce(override.VersionTime, versionTime),
 		URL:                     determineURL(override.URL, modName),
 		LicenceFile:             override.LicenceFile,
-		LicenceType:             override.LicenceType,
+		LicenceType:             licence.Canonical(override.LicenceType),
 		LicenceTextOverrideFile: override.LicenceTextOverrideFile,
+		LicenceText:             override.LicenceText,
+		LicenceSha256:           override.LicenceSha256,
+		OverrideSources:         maps.Clone(override.OverrideSources),
+		Exclude:                 override.Exclude,
+		Reason:                  override.Reason,
+		ReviewedBy:              override.ReviewedBy,
+		ReviewedAt:              override.ReviewedAt,
+		Ticket:                  override.Ticket,
+		Annotations:             maps.Clone(override.Annotations),
 		LocalReplacement:        localReplacement,
+		Context:                 mod.context,
+		Replaced:                mod.Replace != nil,
+		Override:                override.Override,
+		Warnings:                warnings,
 	}
 }
 
@@ -266,42 +762,138 @@ func buildLicenceRegex() *regexp.Regexp {
 	return regexp.MustCompile(regexStr)
 }
 
-func findLicenceFile(root string, licenceRegex *regexp.Regexp) (string, error) {
-	errStopWalk := errors.New("stop walk")
-	var licenceFile string
-	err := filepath.WalkDir(root, func(osPathName string, dirent fs.DirEntry, err error) error {
-		if licenceRegex.MatchString(dirent.Name()) {
-			if dirent.IsDir() {
-				return filepath.SkipDir
+// findLicenceFile returns the first file matching the licence regex in the root directory, then breadth-first in its
+// subdirectories down to the given depth, the root directory being at depth 1. Entries of a directory are searched in
+// lexical order. Directories matching the licence regex and the noise directories in skippedDirs are not searched.
+func findLicenceFile(root string, licenceRegex *regexp.Regexp, maxDepth int) (string, error) {
+	dirs := []string{root}
+	for depth := 1; depth <= maxDepth && len(dirs) > 0; depth++ {
+		var subDirs []string
+		for _, dir := range dirs {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return "", err
+			}
+
+			for _, entry := range entries {
+				name := entry.Name()
+				if entry.IsDir() {
+					if _, skip := skippedDirs[name]; !skip && !licenceRegex.MatchString(name) {
+						subDirs = append(subDirs, filepath.Join(dir, name))
+					}
+					continue
+				}
+
+				if licenceRegex.MatchString(name) {
+					return filepath.Join(dir, name), nil
+				}
 			}
-			licenceFile = osPathName
-			return errStopWalk
 		}
-		return nil
+		dirs = subDirs
+	}
 
-	})
+	return "", errLicenceNotFound
+}
+
+// findReadme returns the README file in the root directory of a module without a licence file.
+func findReadme(root string) (string, error) {
+	entries, err := os.ReadDir(root)
 	if err != nil {
-		if errors.Is(err, errStopWalk) {
-			return licenceFile, nil
-		}
 		return "", err
 	}
 
+	for _, entry := range entries {
+		if !entry.IsDir() && readmeRegex.MatchString(entry.Name()) {
+			return filepath.Join(root, entry.Name()), nil
+		}
+	}
+
 	return "", errLicenceNotFound
 }
 
-func detectLicenceType(classifier *licenseclassifier.License, licenceFile string) (string, error) {
+// checkLicenceHash checks that the licence file of the dependency still has the hash recorded by its override. If the
+// override gives the licence text, the text is compared with the licence file found in the module directory.
+func checkLicenceHash(licenceRegex *regexp.Regexp, searchDepth int, depInfo dependency.Info) error {
+	licenceFile := depInfo.LicenceFile
+	var recordedName, recorded string
+	switch {
+	case depInfo.LicenceText != "":
+		recordedName, recorded = "licence text of the override", depInfo.LicenceText
+	case depInfo.LicenceTextOverrideFile != "":
+		contents, err := os.ReadFile(depInfo.LicenceFile)
+		if err != nil {
+			return fmt.Errorf("failed to read licence content from %s: %w", depInfo.LicenceFile, err)
+		}
+		recordedName, recorded = depInfo.LicenceFile, string(contents)
+	}
+
+	if recordedName != "" {
+		var err error
+		licenceFile, err = findLicenceFile(depInfo.Dir, licenceRegex, searchDepth)
+		if err != nil && !errors.Is(err, errLicenceNotFound) {
+			return fmt.Errorf("failed to find licence file for %s in %s: %w", depInfo.Name, depInfo.Dir, err)
+		}
+	}
+
+	if licenceFile == "" {
+		return fmt.Errorf("no licence file found for %s to check against the licence hash of the override", depInfo.Name)
+	}
+
 	contents, err := os.ReadFile(licenceFile)
 	if err != nil {
-		return "", fmt.Errorf("failed to read licence content from %s: %w", licenceFile, err)
+		return fmt.Errorf("failed to read licence content from %s: %w", licenceFile, err)
+	}
+
+	sum := sha256.Sum256(contents)
+	actual := hex.EncodeToString(sum[:])
+	if strings.EqualFold(actual, depInfo.LicenceSha256) {
+		return nil
 	}
 
-	matches := classifier.MultipleMatch(string(contents), true)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "licence file %s of %s has changed since the override was written. Review the licence and update the override.\n", licenceFile, depInfo.Name)
+	fmt.Fprintf(&sb, "\texpected SHA-256: %s\n\tactual SHA-256:   %s", strings.ToLower(depInfo.LicenceSha256), actual)
+
+	if recordedName != "" {
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(recorded),
+			B:        difflib.SplitLines(string(contents)),
+			FromFile: recordedName,
+			ToFile:   licenceFile,
+			Context:  3,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to compare %s with %s: %w", recordedName, licenceFile, err)
+		}
+
+		sb.WriteString("\n")
+		sb.WriteString(diff)
+	}
+
+	return errors.New(sb.String())
+}
+
+func detectLicenceType(classifier Classifier, licenceFile string) (string, float64, error) {
+	contents, err := os.ReadFile(licenceFile)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read licence content from %s: %w", licenceFile, err)
+	}
+
+	return classifyLicenceText(classifier, licenceFile, string(contents))
+}
+
+// classifyLicenceText detects the licence type of the licence text. The name identifies the text in errors.
+func classifyLicenceText(classifier Classifier, name, text string) (string, float64, error) {
+	matches := classifier.MultipleMatch(text, true)
 	// there should be at least one match
 	if len(matches) < 1 {
-		return "", fmt.Errorf("failed to detect licence type of %s", licenceFile)
+		if m := classifier.NearestMatch(text); m != nil && m.Confidence >= lowConfidenceThreshold {
+			return "", m.Confidence, fmt.Errorf("failed to detect licence type of %s: nearest licence %s has confidence %.2f, below %.2f: %w", name, licence.Canonical(m.Name), m.Confidence, detectionThreshold, errLowConfidence)
+		}
+		return "", 0, fmt.Errorf("failed to detect licence type of %s: %w", name, errUnknownLicence)
 	}
 
-	// matches are sorted by confidence such that the first result has the highest confidence level
-	return matches[0].Name, nil
+	// matches are sorted by confidence such that the first result has the highest confidence level.
+	// Classifier names include deprecated SPDX IDs (e.g. GPL-2.0) that are replaced by their canonical IDs.
+	return licence.Canonical(matches[0].Name), matches[0].Confidence, nil
 }
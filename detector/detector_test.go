@@ -18,20 +18,49 @@
 package detector
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/google/licenseclassifier/stringclassifier"
 	"github.com/stretchr/testify/require"
+	"go.elastic.co/go-licence-detector/cache"
 	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/licence"
+	"go.elastic.co/go-licence-detector/policy"
 )
 
 func TestDetect(t *testing.T) {
+	gplv3, err := os.ReadFile("testdata/github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a/GPLv3")
+	require.NoError(t, err)
+
 	testCases := []struct {
 		name             string
+		modules          string // output of go list -m -json all, testdata/deps.json if empty
+		rules            string // rules file, testdata/rules.json if empty
 		includeIndirect  bool
+		packages         string
+		outboundLicence  string
+		policies         []*policy.Policy
+		requireFile      map[string]struct{}
 		overrides        dependency.Overrides
 		wantDependencies func() *dependency.List
 		wantErr          bool
+		wantFailures     map[string]string // reason of the failure of each module
 	}{
 		{
 			name:            "All",
@@ -46,6 +75,50 @@ func TestDetect(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "LicenceExpressionOverride",
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "MIT OR Apache-2.0"},
+			},
+			wantDependencies: func() *dependency.List {
+				direct := mkDirectDeps()
+				direct[3].LicenceType = "MIT OR Apache-2.0"
+				direct[3].LicenceCategory = "permissive"
+				direct[3].Approval = nil
+				return &dependency.List{Direct: direct}
+			},
+		},
+		{
+			name:            "UnrecognisedOutboundLicence",
+			modules:         "testdata/deps-proprietary.json",
+			includeIndirect: true,
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+			},
+			wantDependencies: func() *dependency.List {
+				return &dependency.List{
+					Indirect: mkIndirectDeps(),
+					Direct:   mkDirectDeps(),
+					Warnings: []string{"failed to detect outbound licence of main module github.com/elastic/proprietary: failed to detect licence type of testdata/github.com/elastic/proprietary/LICENSE: no licence matched. Licence compatibility is not checked, set the outbound licence to check it."},
+				}
+			},
+		},
+		{
+			name:            "OutboundLicenceWithoutCompatibility",
+			modules:         "testdata/deps-zlib.json",
+			includeIndirect: true,
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+			},
+			wantDependencies: func() *dependency.List {
+				return &dependency.List{
+					OutboundLicence: "Zlib",
+					Indirect:        mkIndirectDeps(),
+					Direct:          mkDirectDeps(),
+					Warnings:        []string{"no compatibility information for outbound licence Zlib of main module github.com/elastic/zlib. Licence compatibility is not checked."},
+				}
+			},
+		},
 		{
 			name:            "DirectOnly",
 			includeIndirect: false,
@@ -62,7 +135,15 @@ func TestDetect(t *testing.T) {
 			name:            "WithOverrides",
 			includeIndirect: true,
 			overrides: map[string]dependency.Info{
-				"github.com/davecgh/go-spew":         {Name: "github.com/davecgh/go-spew", URL: "http://example.com/go-spew"},
+				"github.com/davecgh/go-spew": {
+					Name:        "github.com/davecgh/go-spew",
+					URL:         "http://example.com/go-spew",
+					Reason:      "Vanity URL",
+					ReviewedBy:  "legal@example.com",
+					ReviewedAt:  "2024-02-29",
+					Ticket:      "https://github.com/elastic/example/issues/1",
+					Annotations: map[string]string{"note": "Modified by Elastic, see patch"},
+				},
 				"github.com/russross/blackfriday/v2": {Name: "github.com/russross/blackfriday/v2", LicenceType: "MIT"},
 				"github.com/gorhill/cronexpr":        {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
 			},
@@ -73,6 +154,12 @@ func TestDetect(t *testing.T) {
 					d := d
 					if d.Name == "github.com/davecgh/go-spew" {
 						d.URL = "http://example.com/go-spew"
+						d.Override = "github.com/davecgh/go-spew"
+						d.Reason = "Vanity URL"
+						d.ReviewedBy = "legal@example.com"
+						d.ReviewedAt = "2024-02-29"
+						d.Ticket = "https://github.com/elastic/example/issues/1"
+						d.Annotations = map[string]string{"note": "Modified by Elastic, see patch"}
 					}
 					deps.Indirect = append(deps.Indirect, d)
 				}
@@ -81,6 +168,10 @@ func TestDetect(t *testing.T) {
 					d := d
 					if d.Name == "github.com/russross/blackfriday/v2" {
 						d.LicenceType = "MIT"
+						d.LicenceCategory = "permissive"
+						d.LicenceSource = "override"
+						d.LicenceConfidence = 0
+						d.Override = "github.com/russross/blackfriday/v2"
 					}
 					deps.Direct = append(deps.Direct, d)
 				}
@@ -88,6 +179,122 @@ func TestDetect(t *testing.T) {
 				return deps
 			},
 		},
+		{
+			name:            "WithVersionScopedOverrides",
+			includeIndirect: true,
+			overrides: map[string]dependency.Info{
+				"github.com/davecgh/*":                      {Name: "github.com/davecgh/*", URL: "http://example.com/davecgh"},
+				"github.com/davecgh/go-spew@v1.1.0":         {Name: "github.com/davecgh/go-spew@v1.1.0", URL: "http://example.com/go-spew"},
+				"github.com/russross/blackfriday/v2@v2.1.0": {Name: "github.com/russross/blackfriday/v2@v2.1.0", LicenceType: "MIT"},
+				"github.com/gorhill/cronexpr@< v1.0.0":      {Name: "github.com/gorhill/cronexpr", Versions: "< v1.0.0", LicenceType: "GPL-3.0"},
+			},
+			wantDependencies: func() *dependency.List {
+				deps := &dependency.List{}
+
+				for _, d := range mkIndirectDeps() {
+					d := d
+					if d.Name == "github.com/davecgh/go-spew" {
+						d.URL = "http://example.com/go-spew"
+						d.Override = "github.com/davecgh/go-spew@v1.1.0"
+					}
+					deps.Indirect = append(deps.Indirect, d)
+				}
+
+				for _, d := range mkDirectDeps() {
+					d := d
+					switch d.Name {
+					case "github.com/russross/blackfriday/v2":
+						d.Warnings = []string{"override github.com/russross/blackfriday/v2@v2.1.0 was written for a different version than the resolved version v2.0.1"}
+					case "github.com/gorhill/cronexpr":
+						d.Override = "github.com/gorhill/cronexpr@< v1.0.0"
+					}
+					deps.Direct = append(deps.Direct, d)
+				}
+
+				return deps
+			},
+		},
+		{
+			name:            "LicenceFileRequired",
+			includeIndirect: true,
+			requireFile:     map[string]struct{}{"GPL-3.0-only": {}},
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+			},
+			wantErr:      true,
+			wantFailures: map[string]string{"github.com/gorhill/cronexpr": ReasonNoFile},
+		},
+		{
+			name:            "LicenceFileRequiredAndProvided",
+			includeIndirect: true,
+			requireFile:     map[string]struct{}{"GPL-3.0-only": {}},
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceFile: "GPLv3"},
+			},
+			wantDependencies: func() *dependency.List {
+				return &dependency.List{
+					Indirect: mkIndirectDeps(),
+					Direct:   mkDirectOverridenDeps(),
+				}
+			},
+		},
+		{
+			name:            "LicenceFileRequiredAndInlineTextProvided",
+			includeIndirect: true,
+			requireFile:     map[string]struct{}{"GPL-3.0-only": {}},
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {
+					Name:            "github.com/gorhill/cronexpr",
+					LicenceText:     string(gplv3),
+					OverrideSources: map[string]string{"licenceText": "overrides.yaml"},
+				},
+			},
+			wantDependencies: func() *dependency.List {
+				deps := &dependency.List{Indirect: mkIndirectDeps()}
+				for _, d := range mkDirectOverridenDeps() {
+					if d.Name == "github.com/gorhill/cronexpr" {
+						d.LicenceFile = ""
+						d.LicenceText = string(gplv3)
+						d.OverrideSources = map[string]string{"licenceText": "overrides.yaml"}
+					}
+					deps.Direct = append(deps.Direct, d)
+				}
+
+				return deps
+			},
+		},
+		{
+			name:            "WithExcludedDependencies",
+			includeIndirect: true,
+			overrides: map[string]dependency.Info{
+				"github.com/dgryski/*":               {Name: "github.com/dgryski/*", Exclude: true, Reason: "Test fixtures"},
+				"github.com/russross/blackfriday/v2": {Name: "github.com/russross/blackfriday/v2", Exclude: true, Reason: "Replaced by a first-party fork"},
+				"github.com/gorhill/cronexpr":        {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+			},
+			wantDependencies: func() *dependency.List {
+				deps := &dependency.List{
+					Excluded: []dependency.Excluded{
+						{Module: "github.com/russross/blackfriday/v2", Version: "v2.0.1", Override: "github.com/russross/blackfriday/v2", Reason: "Replaced by a first-party fork"},
+						{Module: "github.com/dgryski/go-minhash", Version: "v0.0.0-20170608043002-7fe510aff544", Override: "github.com/dgryski/*", Reason: "Test fixtures"},
+						{Module: "github.com/dgryski/go-spooky", Version: "v0.0.0-20170606183049-ed3d087f40e2", Override: "github.com/dgryski/*", Reason: "Test fixtures"},
+					},
+				}
+
+				for _, d := range mkIndirectDeps() {
+					if d.Name == "github.com/davecgh/go-spew" {
+						deps.Indirect = append(deps.Indirect, d)
+					}
+				}
+
+				for _, d := range mkDirectDeps() {
+					if d.Name != "github.com/russross/blackfriday/v2" {
+						deps.Direct = append(deps.Direct, d)
+					}
+				}
+
+				return deps
+			},
+		},
 		{
 			name:            "WithValidLicenceFileOverride",
 			includeIndirect: true,
@@ -109,6 +316,11 @@ func TestDetect(t *testing.T) {
 				"github.com/russross/blackfriday/v2": {Name: "github.com/russross/blackfriday/v2", LicenceFile: "/path/to/nowhere"},
 			},
 			wantErr: true,
+			wantFailures: map[string]string{
+				"github.com/davecgh/go-spew":         ReasonOverrideInvalid,
+				"github.com/russross/blackfriday/v2": ReasonOverrideInvalid,
+				"github.com/gorhill/cronexpr":        ReasonNoFile,
+			},
 		},
 
 		{
@@ -120,6 +332,169 @@ func TestDetect(t *testing.T) {
 				"github.com/davecgh/go-gk":           {Name: "github.com/davecgh/go-spew", LicenceType: "UNKNOWN"},
 			},
 			wantErr: true,
+			wantFailures: map[string]string{
+				"github.com/davecgh/go-spew":  ReasonDisallowed,
+				"github.com/gorhill/cronexpr": ReasonNoFile,
+			},
+		},
+		{
+			name:            "WithException",
+			rules:           "testdata/rules-denylist.json",
+			includeIndirect: false,
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "AGPL-3.0"},
+			},
+			wantDependencies: func() *dependency.List {
+				exception := dependency.Exception{
+					Module:        "github.com/gorhill/cronexpr",
+					Licence:       "AGPL-3.0-only",
+					Justification: "Test fixture.",
+					Approver:      "legal@example.com",
+					Expires:       "2999-12-31",
+				}
+
+				deps := &dependency.List{Exceptions: []dependency.Exception{exception}}
+				for _, d := range mkDirectDeps() {
+					d := d
+					if d.Name == "github.com/gorhill/cronexpr" {
+						d.LicenceType = "AGPL-3.0-only"
+						d.LicenceCategory = "network-copyleft"
+						d.Approval = nil
+						d.Exception = &exception
+					}
+					deps.Direct = append(deps.Direct, d)
+				}
+
+				return deps
+			},
+		},
+		{
+			name:            "WithPendingReview",
+			includeIndirect: false,
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr":        {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+				"github.com/russross/blackfriday/v2": {Name: "github.com/russross/blackfriday/v2", LicenceType: "GPL-3.0"},
+			},
+			wantDependencies: func() *dependency.List {
+				deps := &dependency.List{
+					PendingReviews: []dependency.PendingReview{
+						{Module: "github.com/russross/blackfriday/v2", Version: "v2.0.1", Licence: "GPL-3.0-only"},
+					},
+				}
+				for _, d := range mkDirectDeps() {
+					d := d
+					if d.Name == "github.com/russross/blackfriday/v2" {
+						d.LicenceType = "GPL-3.0-only"
+						d.LicenceCategory = "strong-copyleft"
+						d.LicenceSource = "override"
+						d.LicenceConfidence = 0
+						d.ReviewRequired = true
+						d.Override = "github.com/russross/blackfriday/v2"
+					}
+					deps.Direct = append(deps.Direct, d)
+				}
+
+				return deps
+			},
+		},
+		{
+			name:            "WithTestOnlyDependency",
+			rules:           "testdata/rules-contexts.json",
+			includeIndirect: false,
+			packages:        "testdata/packages.json",
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "AGPL-3.0"},
+			},
+			wantDependencies: func() *dependency.List {
+				deps := &dependency.List{}
+				for _, d := range mkDirectDeps() {
+					d := d
+					if d.Name == "github.com/gorhill/cronexpr" {
+						// allowed for test dependencies without using the exception
+						d.LicenceType = "AGPL-3.0-only"
+						d.LicenceCategory = "network-copyleft"
+						d.Approval = nil
+						d.Context = "test"
+					}
+					deps.Direct = append(deps.Direct, d)
+				}
+
+				return deps
+			},
+		},
+		{
+			name:            "WithOutboundLicence",
+			includeIndirect: false,
+			outboundLicence: "GPL-3.0",
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+			},
+			wantDependencies: func() *dependency.List {
+				return &dependency.List{
+					OutboundLicence: "GPL-3.0",
+					Direct:          mkDirectDeps(),
+				}
+			},
+		},
+		{
+			name:            "IncompatibleWithOutboundLicence",
+			includeIndirect: false,
+			outboundLicence: "GPL-2.0",
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr":  {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+				"github.com/ekzhu/minhash-lsh": {Name: "github.com/ekzhu/minhash-lsh", LicenceType: "Apache-2.0"},
+			},
+			wantErr: true,
+			// the approval of the GPL-3.0 licence of cronexpr does not make it compatible with GPL-2.0
+			wantFailures: map[string]string{
+				"github.com/ekzhu/minhash-lsh": ReasonDisallowed,
+				"github.com/gorhill/cronexpr":  ReasonDisallowed,
+			},
+		},
+		{
+			name:            "WithPolicies",
+			includeIndirect: true,
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+			},
+			policies: []*policy.Policy{
+				mustPolicy(t, "pseudo-version-override", `pseudoVersion && licenceSource == "override"`, policy.SeverityWarn, "{{.Name}} uses an overridden licence at a pseudo-version"),
+				mustPolicy(t, "low-confidence", `licenceSource == "file" && confidence < 0.99`, policy.SeverityInfo, "{{.Name}}: {{.LicenceType}}"),
+			},
+			wantDependencies: func() *dependency.List {
+				deps := &dependency.List{}
+				for _, d := range mkIndirectDeps() {
+					d := d
+					if d.Name == "github.com/davecgh/go-spew" {
+						d.PolicyResults = []dependency.PolicyResult{
+							{Policy: "low-confidence", Severity: policy.SeverityInfo, Message: "github.com/davecgh/go-spew: ISC"},
+						}
+					}
+					deps.Indirect = append(deps.Indirect, d)
+				}
+
+				for _, d := range mkDirectDeps() {
+					d := d
+					if d.Name == "github.com/gorhill/cronexpr" {
+						d.PolicyResults = []dependency.PolicyResult{
+							{Policy: "pseudo-version-override", Severity: policy.SeverityWarn, Message: "github.com/gorhill/cronexpr uses an overridden licence at a pseudo-version"},
+						}
+					}
+					deps.Direct = append(deps.Direct, d)
+				}
+
+				return deps
+			},
+		},
+		{
+			name:            "LicenceDenied",
+			rules:           "testdata/rules-denylist.json",
+			includeIndirect: true,
+			overrides: map[string]dependency.Info{
+				"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "Elastic-2.0"},
+			},
+			wantErr:      true,
+			wantFailures: map[string]string{"github.com/gorhill/cronexpr": ReasonDisallowed},
 		},
 	}
 
@@ -129,16 +504,43 @@ func TestDetect(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			f, err := os.Open("testdata/deps.json")
+			f, err := os.Open(cmp.Or(tc.modules, "testdata/deps.json"))
 			require.NoError(t, err)
 			defer f.Close()
 
-			rules, err := LoadRules("testdata/rules.json")
+			rules, err := LoadRules(cmp.Or(tc.rules, "testdata/rules.json"))
 			require.NoError(t, err)
 
-			gotDependencies, err := Detect(f, classifier, rules, tc.overrides, tc.includeIndirect)
+			rules.Approvals, err = LoadApprovals("testdata/approvals.json")
+			require.NoError(t, err)
+
+			rules.OutboundLicence = tc.outboundLicence
+			rules.Policies = tc.policies
+			if tc.requireFile != nil {
+				rules.RequireLicenceFile = tc.requireFile
+			}
+
+			var packages io.Reader
+			if tc.packages != "" {
+				pf, err := os.Open(tc.packages)
+				require.NoError(t, err)
+				defer pf.Close()
+				packages = pf
+			}
+
+			d, err := New(WithClassifier(classifier), WithRules(rules), WithOverrides(tc.overrides), WithIndirect(tc.includeIndirect))
+			require.NoError(t, err)
+
+			gotDependencies, err := d.Detect(context.Background(), Source{Modules: f, Packages: packages})
 			if tc.wantErr {
-				require.Error(t, err)
+				var detectionErr *DetectionError
+				require.ErrorAs(t, err, &detectionErr)
+
+				gotFailures := make(map[string]string, len(detectionErr.Modules))
+				for _, m := range detectionErr.Modules {
+					gotFailures[m.Module] = m.Reason
+				}
+				require.Equal(t, tc.wantFailures, gotFailures)
 				return
 			}
 
@@ -148,34 +550,279 @@ func TestDetect(t *testing.T) {
 	}
 }
 
+func TestDetectorDetect(t *testing.T) {
+	classifier, err := NewClassifier("")
+	require.NoError(t, err)
+
+	rules, err := LoadRules("testdata/rules.json")
+	require.NoError(t, err)
+
+	detect := func(ctx context.Context, overrides dependency.Overrides) (*dependency.List, error) {
+		t.Helper()
+
+		d, err := New(WithClassifier(classifier), WithRules(rules), WithOverrides(overrides), WithIndirect(true))
+		require.NoError(t, err)
+
+		f, err := os.Open("testdata/deps.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		return d.Detect(ctx, Source{Modules: f})
+	}
+
+	t.Run("LicenceNotFound", func(t *testing.T) {
+		_, err := detect(context.Background(), nil)
+
+		var notFoundErr *LicenceNotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		require.Equal(t, "github.com/gorhill/cronexpr", notFoundErr.Module)
+		require.Equal(t, "v0.0.0-20161205141322-d520615e531a", notFoundErr.Version)
+	})
+
+	t.Run("DisallowedLicence", func(t *testing.T) {
+		_, err := detect(context.Background(), dependency.Overrides{
+			"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "Elastic-2.0"},
+		})
+
+		var disallowedErr *DisallowedLicenceError
+		require.ErrorAs(t, err, &disallowedErr)
+		require.Equal(t, "github.com/gorhill/cronexpr", disallowedErr.Module)
+		require.Equal(t, "Elastic-2.0", disallowedErr.Licence)
+	})
+
+	t.Run("InvalidOverride", func(t *testing.T) {
+		_, err := detect(context.Background(), dependency.Overrides{
+			"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceFile: "COPYING", Override: "github.com/gorhill/cronexpr"},
+		})
+
+		var overrideErr *InvalidOverrideError
+		require.ErrorAs(t, err, &overrideErr)
+		require.Equal(t, "github.com/gorhill/cronexpr", overrideErr.Override)
+	})
+
+	t.Run("Shorthand", func(t *testing.T) {
+		overrides := dependency.Overrides{
+			"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+		}
+
+		want, err := detect(context.Background(), overrides)
+		require.NoError(t, err)
+
+		f, err := os.Open("testdata/deps.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		have, err := Detect(f, classifier, rules, overrides, true)
+		require.NoError(t, err)
+		require.Equal(t, want, have)
+	})
+
+	t.Run("Workers", func(t *testing.T) {
+		overrides := dependency.Overrides{
+			"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+		}
+
+		var lists []*dependency.List
+		for _, workers := range []int{1, 3, 16} {
+			d, err := New(WithClassifier(classifier), WithRules(rules), WithOverrides(overrides), WithIndirect(true), WithWorkers(workers))
+			require.NoError(t, err)
+
+			f, err := os.Open("testdata/deps.json")
+			require.NoError(t, err)
+			defer f.Close()
+
+			deps, err := d.Detect(context.Background(), Source{Modules: f})
+			require.NoError(t, err)
+			lists = append(lists, deps)
+		}
+
+		require.Equal(t, lists[0], lists[1])
+		require.Equal(t, lists[0], lists[2])
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := detect(ctx, nil)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Cache", func(t *testing.T) {
+		c, err := cache.Open(t.TempDir())
+		require.NoError(t, err)
+
+		overrides := dependency.Overrides{
+			"github.com/gorhill/cronexpr": {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0"},
+		}
+
+		detectCached := func() (*dependency.List, int) {
+			t.Helper()
+
+			counter := &countingClassifier{Classifier: classifier}
+			d, err := New(WithClassifier(counter), WithRules(rules), WithOverrides(overrides), WithIndirect(true), WithCache(c, "db"))
+			require.NoError(t, err)
+
+			f, err := os.Open("testdata/deps.json")
+			require.NoError(t, err)
+			defer f.Close()
+
+			deps, err := d.Detect(context.Background(), Source{Modules: f})
+			require.NoError(t, err)
+
+			return deps, int(counter.calls.Load())
+		}
+
+		want, calls := detectCached()
+		require.Positive(t, calls)
+
+		// only the local replacement is classified again
+		have, calls := detectCached()
+		require.Equal(t, want, have)
+		require.Equal(t, 1, calls)
+
+		// entries are ignored when the licence file does not have the recorded hash
+		entry, ok := c.Get("github.com/davecgh/go-spew", "v1.1.0", "db")
+		require.True(t, ok)
+		entry.LicenceType, entry.LicenceSha256 = "MIT", strings.Repeat("0", 64)
+		require.NoError(t, c.Put(entry))
+
+		have, calls = detectCached()
+		require.Equal(t, want, have)
+		require.Equal(t, 2, calls)
+	})
+}
+
+func TestClassifierHash(t *testing.T) {
+	// the embedded database is assembled at runtime, so its hash must not depend on the encoding of the archive
+	firstDB, err := LoadLicenceDB("")
+	require.NoError(t, err)
+
+	first, err := ClassifierHash(firstDB)
+	require.NoError(t, err)
+
+	secondDB, err := LoadLicenceDB("")
+	require.NoError(t, err)
+
+	second, err := ClassifierHash(secondDB)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Len(t, first, sha256.Size*2)
+}
+
+// countingClassifier counts the texts classified by the wrapped classifier.
+type countingClassifier struct {
+	Classifier
+	calls atomic.Int64
+}
+
+func (c *countingClassifier) MultipleMatch(contents string, includeHeaders bool) stringclassifier.Matches {
+	c.calls.Add(1)
+	return c.Classifier.MultipleMatch(contents, includeHeaders)
+}
+
+func TestDetectReadme(t *testing.T) {
+	classifier, err := NewClassifier("")
+	require.NoError(t, err)
+
+	rules, err := LoadRules("testdata/rules.json")
+	require.NoError(t, err)
+	rules.Policies = []*policy.Policy{
+		mustPolicy(t, "readme-pseudo-version", `pseudoVersion && licenceSource == "readme"`, policy.SeverityWarn, "{{.Name}} has no licence file"),
+	}
+
+	detect := func(modules string) (*dependency.List, error) {
+		t.Helper()
+
+		d, err := New(WithClassifier(classifier), WithRules(rules), WithIndirect(true))
+		require.NoError(t, err)
+
+		return d.Detect(context.Background(), Source{Modules: strings.NewReader(modules)})
+	}
+
+	t.Run("LicenceInReadme", func(t *testing.T) {
+		deps, err := detect(`{"Path": "github.com/elastic/readme", "Version": "v0.0.0-20200101000000-0123456789ab", "Dir": "testdata/github.com/elastic/readme@v0.0.0-20200101000000-0123456789ab"}`)
+		require.NoError(t, err)
+		require.Len(t, deps.Direct, 1)
+
+		got := deps.Direct[0]
+		require.Equal(t, "MIT", got.LicenceType)
+		require.Equal(t, dependency.LicenceSourceReadme, got.LicenceSource)
+		require.Equal(t, "testdata/github.com/elastic/readme@v0.0.0-20200101000000-0123456789ab/README.md", got.LicenceFile)
+		require.Equal(t, []dependency.PolicyResult{
+			{Policy: "readme-pseudo-version", Severity: policy.SeverityWarn, Message: "github.com/elastic/readme has no licence file"},
+		}, got.PolicyResults)
+	})
+
+	t.Run("NoLicenceInReadme", func(t *testing.T) {
+		_, err := detect(`{"Path": "github.com/elastic/nolicence", "Version": "v1.0.0", "Dir": "testdata/github.com/elastic/nolicence@v1.0.0"}`)
+
+		var notFoundErr *LicenceNotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		require.Equal(t, "github.com/elastic/nolicence", notFoundErr.Module)
+	})
+}
+
+func mustPolicy(t *testing.T, name, condition, severity, message string) *policy.Policy {
+	t.Helper()
+
+	p, err := policy.New(name, condition, severity, message)
+	require.NoError(t, err)
+
+	return p
+}
+
+func mkCronexprApproval() *dependency.Approval {
+	return &dependency.Approval{
+		Module:   "github.com/gorhill/cronexpr",
+		Versions: "< v1.0.0",
+		Licence:  "GPL-3.0-only",
+		Reviewer: "legal@example.com",
+		Date:     "2020-05-04",
+		Comment:  "Only used by the scheduler tests.",
+	}
+}
+
 func mkIndirectDeps() []dependency.Info {
 	return []dependency.Info{
 		{
-			Name:        "github.com/davecgh/go-spew",
-			Version:     "v1.1.0",
-			VersionTime: "2016-10-29T20:57:26Z",
-			Dir:         "testdata/github.com/davecgh/go-spew@v1.1.0",
-			LicenceType: "ISC",
-			LicenceFile: "testdata/github.com/davecgh/go-spew@v1.1.0/LICENCE.txt",
-			URL:         "https://github.com/davecgh/go-spew",
+			Name:              "github.com/davecgh/go-spew",
+			Version:           "v1.1.0",
+			VersionTime:       "2016-10-29T20:57:26Z",
+			Dir:               "testdata/github.com/davecgh/go-spew@v1.1.0",
+			LicenceType:       "ISC",
+			LicenceCategory:   "permissive",
+			LicenceFile:       "testdata/github.com/davecgh/go-spew@v1.1.0/LICENCE.txt",
+			URL:               "https://github.com/davecgh/go-spew",
+			Context:           "indirect",
+			LicenceSource:     "file",
+			LicenceConfidence: 0.9705449189985272,
 		},
 		{
-			Name:        "github.com/dgryski/go-minhash",
-			Version:     "v0.0.0-20170608043002-7fe510aff544",
-			VersionTime: "2017-06-08T04:30:02Z",
-			Dir:         "testdata/github.com/dgryski/go-minhash@v0.0.0-20170608043002-7fe510aff544",
-			LicenceType: "MIT",
-			LicenceFile: "testdata/github.com/dgryski/go-minhash@v0.0.0-20170608043002-7fe510aff544/licence",
-			URL:         "https://github.com/dgryski/go-minhash",
+			Name:              "github.com/dgryski/go-minhash",
+			Version:           "v0.0.0-20170608043002-7fe510aff544",
+			VersionTime:       "2017-06-08T04:30:02Z",
+			Dir:               "testdata/github.com/dgryski/go-minhash@v0.0.0-20170608043002-7fe510aff544",
+			LicenceType:       "MIT",
+			LicenceCategory:   "permissive",
+			LicenceFile:       "testdata/github.com/dgryski/go-minhash@v0.0.0-20170608043002-7fe510aff544/licence",
+			URL:               "https://github.com/dgryski/go-minhash",
+			Context:           "indirect",
+			LicenceSource:     "file",
+			LicenceConfidence: 1,
 		},
 		{
-			Name:        "github.com/dgryski/go-spooky",
-			Version:     "v0.0.0-20170606183049-ed3d087f40e2",
-			VersionTime: "2017-06-06T18:30:49Z",
-			Dir:         "testdata/github.com/dgryski/go-spooky@v0.0.0-20170606183049-ed3d087f40e2",
-			LicenceType: "MIT",
-			LicenceFile: "testdata/github.com/dgryski/go-spooky@v0.0.0-20170606183049-ed3d087f40e2/COPYING",
-			URL:         "https://github.com/dgryski/go-spooky",
+			Name:              "github.com/dgryski/go-spooky",
+			Version:           "v0.0.0-20170606183049-ed3d087f40e2",
+			VersionTime:       "2017-06-06T18:30:49Z",
+			Dir:               "testdata/github.com/dgryski/go-spooky@v0.0.0-20170606183049-ed3d087f40e2",
+			LicenceType:       "MIT",
+			LicenceCategory:   "permissive",
+			LicenceFile:       "testdata/github.com/dgryski/go-spooky@v0.0.0-20170606183049-ed3d087f40e2/COPYING",
+			URL:               "https://github.com/dgryski/go-spooky",
+			Context:           "indirect",
+			LicenceSource:     "file",
+			LicenceConfidence: 1,
 		},
 	}
 }
@@ -183,41 +830,60 @@ func mkIndirectDeps() []dependency.Info {
 func mkDirectDeps() []dependency.Info {
 	return []dependency.Info{
 		{
-			Name:        "github.com/ekzhu/minhash-lsh",
-			Version:     "v0.0.0-20171225071031-5c06ee8586a1",
-			VersionTime: "2017-12-25T07:10:31Z",
-			Dir:         "testdata/github.com/ekzhu/minhash-lsh@v0.0.0-20171225071031-5c06ee8586a1",
-			LicenceType: "MIT",
-			LicenceFile: "testdata/github.com/ekzhu/minhash-lsh@v0.0.0-20171225071031-5c06ee8586a1/licence.txt",
-			URL:         "https://github.com/ekzhu/minhash-lsh",
-		},
-		{
-			Name:             "github.com/elastic/test",
-			Version:          "v0.0.1",
-			VersionTime:      "unknown",
-			Dir:              "testdata/github.com/elastic/test",
-			LicenceType:      "MIT",
-			LicenceFile:      "testdata/github.com/elastic/test/LICENSE.txt",
-			URL:              "https://github.com/elastic/test",
-			LocalReplacement: true,
-		},
-		{
-			Name:        "github.com/russross/blackfriday/v2",
-			Version:     "v2.0.1",
-			VersionTime: "2018-09-20T17:16:15Z",
-			Dir:         "testdata/github.com/russross/blackfriday/v2@v2.0.1",
-			LicenceType: "BSD-2-Clause",
-			LicenceFile: "testdata/github.com/russross/blackfriday/v2@v2.0.1/LICENSE.rst",
-			URL:         "https://github.com/russross/blackfriday",
-		},
-		{
-			Name:        "github.com/gorhill/cronexpr",
-			Version:     "v0.0.0-20161205141322-d520615e531a",
-			VersionTime: "2016-12-05T14:13:22Z",
-			Dir:         "testdata/github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a",
-			LicenceType: "GPL-3.0",
-			LicenceFile: "",
-			URL:         "https://github.com/gorhill/cronexpr",
+			Name:              "github.com/ekzhu/minhash-lsh",
+			Version:           "v0.0.0-20171225071031-5c06ee8586a1",
+			VersionTime:       "2017-12-25T07:10:31Z",
+			Dir:               "testdata/github.com/ekzhu/minhash-lsh@v0.0.0-20171225071031-5c06ee8586a1",
+			LicenceType:       "MIT",
+			LicenceCategory:   "permissive",
+			LicenceFile:       "testdata/github.com/ekzhu/minhash-lsh@v0.0.0-20171225071031-5c06ee8586a1/licence.txt",
+			URL:               "https://github.com/ekzhu/minhash-lsh",
+			Context:           "direct",
+			LicenceSource:     "file",
+			LicenceConfidence: 1,
+		},
+		{
+			Name:              "github.com/elastic/test",
+			Version:           "v0.0.1",
+			VersionTime:       "unknown",
+			Dir:               "testdata/github.com/elastic/test",
+			LicenceType:       "MIT",
+			LicenceCategory:   "permissive",
+			LicenceFile:       "testdata/github.com/elastic/test/LICENSE.txt",
+			URL:               "https://github.com/elastic/test",
+			Context:           "direct",
+			LicenceSource:     "file",
+			LicenceConfidence: 1,
+			Replaced:          true,
+			LocalReplacement:  true,
+		},
+		{
+			Name:              "github.com/russross/blackfriday/v2",
+			Version:           "v2.0.1",
+			VersionTime:       "2018-09-20T17:16:15Z",
+			Dir:               "testdata/github.com/russross/blackfriday/v2@v2.0.1",
+			LicenceType:       "BSD-2-Clause",
+			LicenceCategory:   "permissive",
+			LicenceFile:       "testdata/github.com/russross/blackfriday/v2@v2.0.1/LICENSE.rst",
+			URL:               "https://github.com/russross/blackfriday",
+			Context:           "direct",
+			LicenceSource:     "file",
+			LicenceConfidence: 1,
+			Replaced:          true,
+		},
+		{
+			Name:            "github.com/gorhill/cronexpr",
+			Version:         "v0.0.0-20161205141322-d520615e531a",
+			VersionTime:     "2016-12-05T14:13:22Z",
+			Dir:             "testdata/github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a",
+			LicenceType:     "GPL-3.0-only",
+			LicenceCategory: "strong-copyleft",
+			Approval:        mkCronexprApproval(),
+			LicenceFile:     "",
+			URL:             "https://github.com/gorhill/cronexpr",
+			Context:         "direct",
+			LicenceSource:   "override",
+			Override:        "github.com/gorhill/cronexpr",
 		},
 	}
 }
@@ -225,43 +891,294 @@ func mkDirectDeps() []dependency.Info {
 func mkDirectOverridenDeps() []dependency.Info {
 	return []dependency.Info{
 		{
-			Name:        "github.com/ekzhu/minhash-lsh",
-			Version:     "v0.0.0-20171225071031-5c06ee8586a1",
-			VersionTime: "2017-12-25T07:10:31Z",
-			Dir:         "testdata/github.com/ekzhu/minhash-lsh@v0.0.0-20171225071031-5c06ee8586a1",
-			LicenceType: "MIT",
-			LicenceFile: "testdata/github.com/ekzhu/minhash-lsh@v0.0.0-20171225071031-5c06ee8586a1/licence.txt",
-			URL:         "https://github.com/ekzhu/minhash-lsh",
-		},
-		{
-			Name:             "github.com/elastic/test",
-			Version:          "v0.0.1",
-			VersionTime:      "unknown",
-			Dir:              "testdata/github.com/elastic/test",
-			LicenceType:      "MIT",
-			LicenceFile:      "testdata/github.com/elastic/test/LICENSE.txt",
-			URL:              "https://github.com/elastic/test",
-			LocalReplacement: true,
-		},
-		{
-			Name:        "github.com/russross/blackfriday/v2",
-			Version:     "v2.0.1",
-			VersionTime: "2018-09-20T17:16:15Z",
-			Dir:         "testdata/github.com/russross/blackfriday/v2@v2.0.1",
-			LicenceType: "BSD-2-Clause",
-			LicenceFile: "testdata/github.com/russross/blackfriday/v2@v2.0.1/LICENSE.rst",
-			URL:         "https://github.com/russross/blackfriday",
-		},
-		{
-			Name:        "github.com/gorhill/cronexpr",
-			Version:     "v0.0.0-20161205141322-d520615e531a",
-			VersionTime: "2016-12-05T14:13:22Z",
-			Dir:         "testdata/github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a",
-			LicenceType: "GPL-3.0",
-			LicenceFile: "testdata/github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a/GPLv3",
-			URL:         "https://github.com/gorhill/cronexpr",
+			Name:              "github.com/ekzhu/minhash-lsh",
+			Version:           "v0.0.0-20171225071031-5c06ee8586a1",
+			VersionTime:       "2017-12-25T07:10:31Z",
+			Dir:               "testdata/github.com/ekzhu/minhash-lsh@v0.0.0-20171225071031-5c06ee8586a1",
+			LicenceType:       "MIT",
+			LicenceCategory:   "permissive",
+			LicenceFile:       "testdata/github.com/ekzhu/minhash-lsh@v0.0.0-20171225071031-5c06ee8586a1/licence.txt",
+			URL:               "https://github.com/ekzhu/minhash-lsh",
+			Context:           "direct",
+			LicenceSource:     "file",
+			LicenceConfidence: 1,
+		},
+		{
+			Name:              "github.com/elastic/test",
+			Version:           "v0.0.1",
+			VersionTime:       "unknown",
+			Dir:               "testdata/github.com/elastic/test",
+			LicenceType:       "MIT",
+			LicenceCategory:   "permissive",
+			LicenceFile:       "testdata/github.com/elastic/test/LICENSE.txt",
+			URL:               "https://github.com/elastic/test",
+			Context:           "direct",
+			LicenceSource:     "file",
+			LicenceConfidence: 1,
+			Replaced:          true,
+			LocalReplacement:  true,
+		},
+		{
+			Name:              "github.com/russross/blackfriday/v2",
+			Version:           "v2.0.1",
+			VersionTime:       "2018-09-20T17:16:15Z",
+			Dir:               "testdata/github.com/russross/blackfriday/v2@v2.0.1",
+			LicenceType:       "BSD-2-Clause",
+			LicenceCategory:   "permissive",
+			LicenceFile:       "testdata/github.com/russross/blackfriday/v2@v2.0.1/LICENSE.rst",
+			URL:               "https://github.com/russross/blackfriday",
+			Context:           "direct",
+			LicenceSource:     "file",
+			LicenceConfidence: 1,
+			Replaced:          true,
+		},
+		{
+			Name:              "github.com/gorhill/cronexpr",
+			Version:           "v0.0.0-20161205141322-d520615e531a",
+			VersionTime:       "2016-12-05T14:13:22Z",
+			Dir:               "testdata/github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a",
+			LicenceType:       "GPL-3.0-only",
+			LicenceCategory:   "strong-copyleft",
+			Approval:          mkCronexprApproval(),
+			LicenceFile:       "testdata/github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a/GPLv3",
+			URL:               "https://github.com/gorhill/cronexpr",
+			Context:           "direct",
+			LicenceSource:     "override-file",
+			LicenceConfidence: 1,
+			Override:          "github.com/gorhill/cronexpr",
+		},
+	}
+}
+
+func TestFindLicenceFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    []string
+		maxDepth int
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "RootBeforeSubdirectory",
+			files:    []string{"a/LICENSE", "z/LICENSE.rst", "LICENSE.rst"},
+			maxDepth: DefaultSearchDepth,
+			want:     "LICENSE.rst",
+		},
+		{
+			name:     "BreadthFirst",
+			files:    []string{"a/b/c/LICENSE", "z/COPYING"},
+			maxDepth: DefaultSearchDepth,
+			want:     "z/COPYING",
+		},
+		{
+			name:     "MaxDepth",
+			files:    []string{"a/b/c/LICENSE"},
+			maxDepth: DefaultSearchDepth,
+			wantErr:  errLicenceNotFound,
+		},
+		{
+			name:     "DeeperMaxDepth",
+			files:    []string{"a/b/c/LICENSE"},
+			maxDepth: 4,
+			want:     "a/b/c/LICENSE",
+		},
+		{
+			name:     "RootOnly",
+			files:    []string{"a/LICENSE"},
+			maxDepth: 1,
+			wantErr:  errLicenceNotFound,
+		},
+		{
+			name:     "SkippedDirectories",
+			files:    []string{".git/LICENSE", "node_modules/LICENSE", "testdata/LICENSE", "vendor/LICENSE", "x/COPYING"},
+			maxDepth: DefaultSearchDepth,
+			want:     "x/COPYING",
+		},
+		{
+			name:     "LicenceDirectory",
+			files:    []string{"LICENSES/MIT.txt", "b/LICENSE"},
+			maxDepth: DefaultSearchDepth,
+			want:     "b/LICENSE",
+		},
+		{
+			name:     "NotFound",
+			files:    []string{"README.md", "docs/index.md"},
+			maxDepth: DefaultSearchDepth,
+			wantErr:  errLicenceNotFound,
+		},
+	}
+
+	licenceRegex := buildLicenceRegex()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, f := range tc.files {
+				path := filepath.Join(root, filepath.FromSlash(f))
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				require.NoError(t, os.WriteFile(path, []byte("licence"), 0o644))
+			}
+
+			have, err := findLicenceFile(root, licenceRegex, tc.maxDepth)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, filepath.Join(root, filepath.FromSlash(tc.want)), have)
+		})
+	}
+
+	t.Run("MissingDirectory", func(t *testing.T) {
+		_, err := findLicenceFile(filepath.Join(t.TempDir(), "missing"), licenceRegex, DefaultSearchDepth)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+func TestDetermineOutboundLicence(t *testing.T) {
+	classifier, err := NewClassifier("")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		rules *Rules
+		main  *module
+		want  string
+	}{
+		{
+			name:  "FromRules",
+			rules: &Rules{OutboundLicence: "Apache-2.0"},
+			main:  &module{Path: "github.com/elastic/test", Dir: "testdata/github.com/elastic/test"},
+			want:  "Apache-2.0",
+		},
+		{
+			name:  "FromLicenceFile",
+			rules: &Rules{},
+			main:  &module{Path: "github.com/elastic/test", Dir: "testdata/github.com/elastic/test"},
+			want:  "MIT",
+		},
+		{
+			name:  "MissingDirectory",
+			rules: &Rules{},
+			main:  &module{Path: "github.com/charith-elastic/licence-detector", Dir: "testdata/github.com/charith-elastic/license-detector"},
+		},
+		{
+			name:  "NoMainModule",
+			rules: &Rules{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := determineOutboundLicence(classifier, tc.rules, tc.main)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDetectLicenceType(t *testing.T) {
+	testCases := []struct {
+		name        string
+		licenceFile string
+		want        string
+	}{
+		{
+			name:        "Elastic-2.0",
+			licenceFile: "testdata/licences/Elastic-2.0.txt",
+			want:        "Elastic-2.0",
+		},
+		{
+			name:        "BUSL-1.1",
+			licenceFile: "testdata/licences/BUSL-1.1.txt",
+			want:        "BUSL-1.1",
+		},
+		{
+			name:        "SSPL-1.0",
+			licenceFile: "../assets/licences/SSPL-1.0.txt",
+			want:        "SSPL-1.0",
+		},
+	}
+
+	classifier, err := NewClassifier("")
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			have, confidence, err := detectLicenceType(classifier, tc.licenceFile)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, have)
+			require.GreaterOrEqual(t, confidence, detectionThreshold)
+		})
+	}
+}
+
+func TestCheckLicenceHash(t *testing.T) {
+	const (
+		recordedText = "MIT License\n\nCopyright (c) 2017 Example\n"
+		changedText  = "MIT License\n\nCopyright (c) 2017 Example\n\nThe Software may not be used for evil.\n"
+		recordedHash = "3d5d4d3b1ad8e8a2f7a1e46a7d83ae7f1cebe3f5a5d6bb66ebd4f8d5b9b3c0c2"
+	)
+
+	dir := t.TempDir()
+	moduleDir := filepath.Join(dir, "module")
+	require.NoError(t, os.Mkdir(moduleDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir, "LICENSE"), []byte(changedText), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "recorded.txt"), []byte(recordedText), 0o600))
+
+	changedSum := sha256.Sum256([]byte(changedText))
+	changedHash := hex.EncodeToString(changedSum[:])
+
+	testCases := []struct {
+		name     string
+		depInfo  dependency.Info
+		wantErrs []string
+	}{
+		{
+			name:    "Match",
+			depInfo: dependency.Info{Name: "example.com/a", Dir: moduleDir, LicenceFile: filepath.Join(moduleDir, "LICENSE"), LicenceSha256: changedHash},
+		},
+		{
+			name:    "MatchIgnoresCase",
+			depInfo: dependency.Info{Name: "example.com/a", Dir: moduleDir, LicenceFile: filepath.Join(moduleDir, "LICENSE"), LicenceSha256: strings.ToUpper(changedHash)},
+		},
+		{
+			name:     "Mismatch",
+			depInfo:  dependency.Info{Name: "example.com/a", Dir: moduleDir, LicenceFile: filepath.Join(moduleDir, "LICENSE"), LicenceSha256: recordedHash},
+			wantErrs: []string{"has changed", "expected SHA-256: " + recordedHash, "actual SHA-256:   " + changedHash},
+		},
+		{
+			name: "MismatchWithRecordedText",
+			depInfo: dependency.Info{
+				Name:                    "example.com/a",
+				Dir:                     moduleDir,
+				LicenceFile:             filepath.Join(dir, "recorded.txt"),
+				LicenceTextOverrideFile: "recorded.txt",
+				LicenceSha256:           recordedHash,
+			},
+			wantErrs: []string{"actual SHA-256:   " + changedHash, "+++ " + filepath.Join(moduleDir, "LICENSE"), "+The Software may not be used for evil."},
+		},
+		{
+			name:     "NoLicenceFile",
+			depInfo:  dependency.Info{Name: "example.com/a", Dir: dir, LicenceSha256: recordedHash},
+			wantErrs: []string{"no licence file found for example.com/a"},
 		},
 	}
+
+	licenceRegex := buildLicenceRegex()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkLicenceHash(licenceRegex, DefaultSearchDepth, tc.depInfo)
+			if len(tc.wantErrs) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			for _, want := range tc.wantErrs {
+				require.ErrorContains(t, err, want)
+			}
+		})
+	}
 }
 
 func TestDetermineURL(t *testing.T) {
@@ -306,3 +1223,105 @@ func TestDetermineURL(t *testing.T) {
 		})
 	}
 }
+
+func TestClassifyLicenceTextFailures(t *testing.T) {
+	classifier, err := NewClassifier("")
+	require.NoError(t, err)
+
+	mit, ok := licence.Text("MIT")
+	require.True(t, ok)
+
+	testCases := []struct {
+		name       string
+		text       string
+		wantReason string
+	}{
+		{
+			name:       "Unknown",
+			text:       "All rights reserved. Ask us before using this code.",
+			wantReason: ReasonUnknown,
+		},
+		{
+			name:       "LowConfidence",
+			text:       mit[:len(mit)*4/5],
+			wantReason: ReasonLowConfidence,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := classifyLicenceText(classifier, "text", tc.text)
+			require.Error(t, err)
+			require.Equal(t, tc.wantReason, classificationReason(err))
+		})
+	}
+}
+
+func TestDetectionError(t *testing.T) {
+	errs := []*ModuleError{
+		{Module: "example.com/a", Version: "v1.0.0", Reason: ReasonNoFile, Err: errors.New("no licence file found for example.com/a")},
+		{Module: "example.com/b", Version: "v2.0.0", Reason: ReasonDisallowed, Err: errors.New("dependency example.com/b uses licence GPL-3.0-only")},
+	}
+
+	err := fmt.Errorf("failed to detect licences: %w", &DetectionError{Modules: errs})
+	require.EqualError(t, err, "failed to detect licences: licences of 2 modules are not accepted:\n"+
+		"  example.com/a@v1.0.0 (no-file): no licence file found for example.com/a\n"+
+		"  example.com/b@v2.0.0 (disallowed): dependency example.com/b uses licence GPL-3.0-only")
+
+	var modErr *ModuleError
+	require.ErrorAs(t, err, &modErr)
+	require.Equal(t, "example.com/a", modErr.Module)
+
+	require.EqualError(t, &DetectionError{Modules: errs[1:]}, "dependency example.com/b uses licence GPL-3.0-only")
+}
+
+func BenchmarkDetect(b *testing.B) {
+	const numModules = 500
+
+	classifier, err := NewClassifier("")
+	require.NoError(b, err)
+
+	rules, err := LoadRules("testdata/rules.json")
+	require.NoError(b, err)
+
+	var licences [][]byte
+	for _, f := range []string{
+		"testdata/github.com/davecgh/go-spew@v1.1.0/LICENCE.txt",
+		"testdata/github.com/dgryski/go-spooky@v0.0.0-20170606183049-ed3d087f40e2/COPYING",
+		"testdata/github.com/russross/blackfriday/v2@v2.0.1/LICENSE.rst",
+	} {
+		contents, err := os.ReadFile(f)
+		require.NoError(b, err)
+		licences = append(licences, contents)
+	}
+
+	// each module has its own directory, so that the benchmark measures the search and classification of many files
+	root := b.TempDir()
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	require.NoError(b, enc.Encode(map[string]any{"Path": "example.com/main", "Main": true, "Dir": root}))
+	for i := range numModules {
+		path := fmt.Sprintf("example.com/mod%d", i)
+		dir := filepath.Join(root, "mod", fmt.Sprintf("mod%d@v1.0.0", i))
+		require.NoError(b, os.MkdirAll(dir, 0o755))
+		require.NoError(b, os.WriteFile(filepath.Join(dir, "LICENSE"), licences[i%len(licences)], 0o644))
+		require.NoError(b, enc.Encode(map[string]any{"Path": path, "Version": "v1.0.0", "Indirect": i%2 == 1, "Dir": dir}))
+	}
+
+	for _, workers := range slices.Compact(slices.Sorted(slices.Values([]int{1, 2, 4, runtime.NumCPU()}))) {
+		b.Run(fmt.Sprintf("Workers%d", workers), func(b *testing.B) {
+			d, err := New(WithClassifier(classifier), WithRules(rules), WithIndirect(true), WithWorkers(workers))
+			require.NoError(b, err)
+
+			for b.Loop() {
+				deps, err := d.Detect(context.Background(), Source{Modules: bytes.NewReader(data.Bytes())})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if n := len(deps.Direct) + len(deps.Indirect); n != numModules {
+					b.Fatalf("detected %d modules, want %d", n, numModules)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package detector
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Reasons for which the licence of a module is not accepted.
+const (
+	ReasonNoFile          = "no-file"          // no licence file was found and no licence type is given by an override
+	ReasonUnknown         = "unknown"          // the classifier does not recognise the licence
+	ReasonLowConfidence   = "low-confidence"   // the classifier recognises the licence with too little confidence
+	ReasonDisallowed      = "disallowed"       // the licence or the module is not allowed by the rules
+	ReasonOverrideInvalid = "override-invalid" // the override of the module cannot be applied
+)
+
+var (
+	// errUnknownLicence is returned by the classification of a text that does not match any licence.
+	errUnknownLicence = errors.New("no licence matched")
+	// errLowConfidence is returned by the classification of a text whose nearest licence is below the detection threshold.
+	errLowConfidence = errors.New("confidence below the detection threshold")
+)
+
+// ModuleError is the reason why the licence of a module is not accepted. Err is one of *LicenceNotFoundError,
+// *UnknownLicenceError, *DisallowedLicenceError or *InvalidOverrideError.
+type ModuleError struct {
+	Module  string
+	Version string
+	Reason  string // one of the Reason constants
+	Err     error
+}
+
+func (e *ModuleError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ModuleError) Unwrap() error {
+	return e.Err
+}
+
+// LicenceNotFoundError is the error of a module without a licence file whose licence type is not given by an
+// override, or whose licence requires the licence file of the module.
+type LicenceNotFoundError struct {
+	Module  string
+	Version string
+	Licence string // licence that requires the licence file, if any
+}
+
+func (e *LicenceNotFoundError) Error() string {
+	if e.Licence != "" {
+		return fmt.Sprintf("no licence file found for %s. The %s licence carries the copyright notice of the module, so the canonical licence text is not enough. Add an override entry with licence file to continue.", e.Module, e.Licence)
+	}
+
+	return fmt.Sprintf("no licence file found for %s. Add an override entry with licence type to continue.", e.Module)
+}
+
+// UnknownLicenceError is the error of a module whose licence text is not recognised by the classifier, or only with
+// a confidence below the detection threshold.
+type UnknownLicenceError struct {
+	Module      string
+	Version     string
+	LicenceFile string // licence file that is not recognised, empty if the licence text is given by an override
+	Err         error
+}
+
+func (e *UnknownLicenceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UnknownLicenceError) Unwrap() error {
+	return e.Err
+}
+
+// DisallowedLicenceError is the error of a module whose licence or version is not allowed by the rules.
+type DisallowedLicenceError struct {
+	Module  string
+	Version string
+	Licence string
+	Err     error
+}
+
+func (e *DisallowedLicenceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DisallowedLicenceError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidOverrideError is the error of a module whose override cannot be applied, such as an override whose licence
+// file does not exist.
+type InvalidOverrideError struct {
+	Module   string
+	Version  string
+	Override string // key of the override
+	Err      error
+}
+
+func (e *InvalidOverrideError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidOverrideError) Unwrap() error {
+	return e.Err
+}
+
+// DetectionError is returned by Detect when the licence of one or more modules is not accepted. All modules are
+// processed before it is returned.
+type DetectionError struct {
+	Modules []*ModuleError
+}
+
+func (e *DetectionError) Error() string {
+	if len(e.Modules) == 1 {
+		return e.Modules[0].Error()
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "licences of %d modules are not accepted:", len(e.Modules))
+	for _, m := range e.Modules {
+		fmt.Fprintf(&sb, "\n  %s@%s (%s): %v", m.Module, m.Version, m.Reason, m.Err)
+	}
+
+	return sb.String()
+}
+
+// Unwrap returns the errors of the modules.
+func (e *DetectionError) Unwrap() []error {
+	errs := make([]error, len(e.Modules))
+	for i, m := range e.Modules {
+		errs[i] = m
+	}
+
+	return errs
+}
+
+// classificationReason returns the reason code of an error returned by the classification of a licence text, or an
+// empty string if the error is not a classification failure.
+func classificationReason(err error) string {
+	switch {
+	case errors.Is(err, errLowConfidence):
+		return ReasonLowConfidence
+	case errors.Is(err, errUnknownLicence):
+		return ReasonUnknown
+	default:
+		return ""
+	}
+}
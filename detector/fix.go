@@ -0,0 +1,264 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package detector
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/licence"
+)
+
+// maxCandidates is the maximum number of licence types suggested for a module.
+const maxCandidates = 3
+
+// candidateFileRegex matches the names of files that may contain the licence of a module when the licence file is not
+// found by the usual file names.
+var candidateFileRegex = regexp.MustCompile(`(?i)(li[cs]en[cs]e|copy(left|right|ing)|notice|legal|readme|gpl|bsd|mit|apache)`)
+
+// Suggestion is an override suggested by SuggestOverrides for a module whose licence cannot be detected.
+type Suggestion struct {
+	Module     string      `json:"module"`
+	Version    string      `json:"version"`
+	Problem    string      `json:"problem"`              // why the licence cannot be detected
+	File       string      `json:"file,omitempty"`       // file most likely to contain the licence of the module
+	Candidates []Candidate `json:"candidates,omitempty"` // licence types of the file, from the most to the least likely
+}
+
+// Candidate is a licence type suggested by the classifier.
+type Candidate struct {
+	LicenceType string  `json:"licenceType"`
+	Confidence  float64 `json:"confidence"`
+}
+
+// SuggestOverrides detects the licences of all the dependencies read from data (output of go list -m -json all) with
+// the given overrides and suggests overrides for the modules that fail because no licence file is found, their
+// licence is not recognised or the licence file of their override does not exist. Licence rules are not checked as
+// they cannot be fixed by overrides. Licence files are searched down to searchDepth levels, or DefaultSearchDepth if
+// searchDepth is not positive.
+func SuggestOverrides(data io.Reader, classifier Classifier, overrides dependency.Overrides, searchDepth int) ([]Suggestion, error) {
+	modules, err := io.ReadAll(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dependencies: %w", err)
+	}
+
+	deps, err := parseDependencies(bytes.NewReader(modules), true)
+	if err != nil {
+		return nil, err
+	}
+
+	// the failures only identify the modules, so their directories are taken from the dependency information
+	dirs := make(map[string]string)
+	for _, mod := range slices.Concat(deps.direct, deps.indirect) {
+		depInfo := mkDepInfo(mod, overrides)
+		dirs[depInfo.Name+"@"+depInfo.Version] = depInfo.Dir
+	}
+
+	// the empty rules reject every licence, so the modules whose licence is detected fail with ReasonDisallowed
+	d, err := New(WithClassifier(classifier), WithRules(&Rules{}), WithOverrides(overrides), WithIndirect(true), WithSearchDepth(searchDepth))
+	if err != nil {
+		return nil, err
+	}
+
+	var detectionErr *DetectionError
+	if _, err := d.Detect(context.Background(), Source{Modules: bytes.NewReader(modules)}); !errors.As(err, &detectionErr) {
+		return nil, err
+	}
+
+	var suggestions []Suggestion
+	for _, modErr := range detectionErr.Modules {
+		suggestion := Suggestion{Module: modErr.Module, Version: modErr.Version}
+
+		var (
+			unknownErr *UnknownLicenceError
+			pathErr    *fs.PathError
+		)
+		switch {
+		case modErr.Reason == ReasonNoFile:
+			suggestion.Problem = "no licence file found"
+		case modErr.Reason == ReasonOverrideInvalid && errors.Is(modErr, fs.ErrNotExist) && errors.As(modErr, &pathErr):
+			suggestion.Problem = fmt.Sprintf("licence file %s does not exist", pathErr.Path)
+		case (modErr.Reason == ReasonUnknown || modErr.Reason == ReasonLowConfidence) && errors.As(modErr, &unknownErr) && unknownErr.LicenceFile != "":
+			contents, err := os.ReadFile(unknownErr.LicenceFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read licence content from %s: %w", unknownErr.LicenceFile, err)
+			}
+
+			suggestion.Problem = fmt.Sprintf("licence type of %s unknown", unknownErr.LicenceFile)
+			if modErr.Reason == ReasonLowConfidence {
+				suggestion.Problem = fmt.Sprintf("licence type of %s detected with low confidence", unknownErr.LicenceFile)
+			}
+			suggestion.File, suggestion.Candidates = unknownErr.LicenceFile, candidateLicences(classifier, string(contents))
+		default:
+			continue
+		}
+
+		// look for another file containing the licence if the module has no usable licence file
+		if suggestion.File == "" {
+			if suggestion.File, suggestion.Candidates, err = findCandidateFile(classifier, dirs[modErr.Module+"@"+modErr.Version]); err != nil {
+				return nil, err
+			}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	slices.SortFunc(suggestions, func(a, b Suggestion) int {
+		return cmp.Or(cmp.Compare(a.Module, b.Module), cmp.Compare(a.Version, b.Version))
+	})
+
+	return suggestions, nil
+}
+
+// WriteSuggestions writes the suggested overrides to the given file as YAML documents, with the problem and the
+// candidate licence types of each module as comments. Candidate licence files are copied to
+// licences/<module path>/LICENCE under the directory of the file, which is the layout of example/overrides.
+func WriteSuggestions(file string, suggestions []Suggestion) error {
+	rootDir := filepath.Dir(file)
+
+	var buf bytes.Buffer
+	for i, s := range suggestions {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+
+		fmt.Fprintf(&buf, "# %s@%s: %s.\n", s.Module, s.Version, s.Problem)
+		if s.File != "" {
+			fmt.Fprintf(&buf, "# Candidate licence file: %s\n", s.File)
+		}
+
+		if len(s.Candidates) == 0 {
+			buf.WriteString("# No licence matched. Set the licence type after reviewing the module.\n")
+		} else {
+			buf.WriteString("# Candidate licence types:\n")
+			for _, c := range s.Candidates {
+				fmt.Fprintf(&buf, "#   %s (confidence %.2f)\n", c.LicenceType, c.Confidence)
+			}
+		}
+
+		writeYAMLField(&buf, "name", s.Module)
+
+		if s.File != "" {
+			textFile := filepath.Join("licences", filepath.FromSlash(s.Module), "LICENCE")
+			if err := copyFile(s.File, filepath.Join(rootDir, textFile)); err != nil {
+				return fmt.Errorf("failed to copy licence file of %s: %w", s.Module, err)
+			}
+			writeYAMLField(&buf, "licenceTextOverrideFile", filepath.ToSlash(textFile))
+		}
+
+		var licenceType string
+		if len(s.Candidates) > 0 {
+			licenceType = s.Candidates[0].LicenceType
+		}
+		writeYAMLField(&buf, "licenceType", licenceType)
+	}
+
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write suggested overrides to %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// findCandidateFile returns the file at the root of the module directory that is the most likely to contain its
+// licence, along with its candidate licence types. Files without any licence match are ignored.
+func findCandidateFile(classifier Classifier, dir string) (string, []Candidate, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read module directory %s: %w", dir, err)
+	}
+
+	var bestFile string
+	var bestCandidates []Candidate
+	for _, e := range entries {
+		if e.IsDir() || !candidateFileRegex.MatchString(e.Name()) {
+			continue
+		}
+
+		path := filepath.Join(dir, e.Name())
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		if len(classifier.MultipleMatch(string(contents), true)) == 0 {
+			continue
+		}
+
+		candidates := candidateLicences(classifier, string(contents))
+		if bestCandidates == nil || candidates[0].Confidence > bestCandidates[0].Confidence {
+			bestFile, bestCandidates = path, candidates
+		}
+	}
+
+	return bestFile, bestCandidates, nil
+}
+
+// candidateLicences returns the most likely licence types of the text. If no licence matches with enough confidence,
+// the nearest licence is returned.
+func candidateLicences(classifier Classifier, text string) []Candidate {
+	var candidates []Candidate
+	for _, m := range classifier.MultipleMatch(text, true) {
+		licenceType := licence.Canonical(m.Name)
+		if slices.ContainsFunc(candidates, func(c Candidate) bool { return c.LicenceType == licenceType }) {
+			continue
+		}
+
+		candidates = append(candidates, Candidate{LicenceType: licenceType, Confidence: m.Confidence})
+		if len(candidates) == maxCandidates {
+			break
+		}
+	}
+
+	if len(candidates) == 0 {
+		if m := classifier.NearestMatch(text); m != nil {
+			candidates = append(candidates, Candidate{LicenceType: licence.Canonical(m.Name), Confidence: m.Confidence})
+		}
+	}
+
+	return candidates
+}
+
+// writeYAMLField writes the field as YAML. JSON strings are valid YAML double-quoted strings.
+func writeYAMLField(buf *bytes.Buffer, name, value string) {
+	encoded, _ := json.Marshal(value)
+	fmt.Fprintf(buf, "%s: %s\n", name, encoded)
+}
+
+func copyFile(src, dst string) error {
+	contents, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(dst, contents, 0o644)
+}
@@ -0,0 +1,160 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/licence"
+)
+
+func TestSuggestOverrides(t *testing.T) {
+	classifier, err := NewClassifier("")
+	require.NoError(t, err)
+
+	unknownDir := filepath.Join(t.TempDir(), "unknown")
+	require.NoError(t, os.Mkdir(unknownDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(unknownDir, "LICENSE"), []byte("All rights reserved, ask us first."), 0o600))
+
+	mit, ok := licence.Text("MIT")
+	require.True(t, ok)
+	truncated := mit[:len(mit)*4/5]
+
+	lowConfidenceDir := filepath.Join(t.TempDir(), "truncated")
+	require.NoError(t, os.Mkdir(lowConfidenceDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(lowConfidenceDir, "LICENSE"), []byte(truncated), 0o600))
+
+	deps := `{"Path": "github.com/elastic/unknown", "Version": "v1.0.0", "Dir": "` + unknownDir + `"}` +
+		`{"Path": "github.com/elastic/truncated", "Version": "v1.0.0", "Dir": "` + lowConfidenceDir + `"}`
+	cronexprDir := "testdata/github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a"
+
+	testCases := []struct {
+		name      string
+		overrides dependency.Overrides
+		want      []Suggestion
+	}{
+		{
+			name: "NoOverrides",
+			want: []Suggestion{
+				{
+					Module:     "github.com/elastic/truncated",
+					Version:    "v1.0.0",
+					Problem:    "licence type of " + filepath.Join(lowConfidenceDir, "LICENSE") + " detected with low confidence",
+					File:       filepath.Join(lowConfidenceDir, "LICENSE"),
+					Candidates: candidateLicences(classifier, truncated),
+				},
+				{
+					Module:     "github.com/elastic/unknown",
+					Version:    "v1.0.0",
+					Problem:    "licence type of " + filepath.Join(unknownDir, "LICENSE") + " unknown",
+					File:       filepath.Join(unknownDir, "LICENSE"),
+					Candidates: candidateLicences(classifier, "All rights reserved, ask us first."),
+				},
+				{
+					Module:     "github.com/gorhill/cronexpr",
+					Version:    "v0.0.0-20161205141322-d520615e531a",
+					Problem:    "no licence file found",
+					File:       filepath.Join(cronexprDir, "GPLv3"),
+					Candidates: []Candidate{{LicenceType: "GPL-3.0-only", Confidence: 1}},
+				},
+			},
+		},
+		{
+			name: "MissingLicenceFile",
+			overrides: dependency.Overrides{
+				"github.com/elastic/truncated": {Name: "github.com/elastic/truncated", LicenceType: "MIT"},
+				"github.com/elastic/unknown":   {Name: "github.com/elastic/unknown", LicenceType: "LicenseRef-Proprietary"},
+				"github.com/gorhill/cronexpr":  {Name: "github.com/gorhill/cronexpr", LicenceFile: "LICENSE"},
+			},
+			want: []Suggestion{
+				{
+					Module:     "github.com/gorhill/cronexpr",
+					Version:    "v0.0.0-20161205141322-d520615e531a",
+					Problem:    "licence file " + filepath.Join(cronexprDir, "LICENSE") + " does not exist",
+					File:       filepath.Join(cronexprDir, "GPLv3"),
+					Candidates: []Candidate{{LicenceType: "GPL-3.0-only", Confidence: 1}},
+				},
+			},
+		},
+		{
+			name: "Fixed",
+			overrides: dependency.Overrides{
+				"github.com/elastic/truncated": {Name: "github.com/elastic/truncated", LicenceType: "MIT"},
+				"github.com/elastic/unknown":   {Name: "github.com/elastic/unknown", LicenceType: "LicenseRef-Proprietary"},
+				"github.com/gorhill/cronexpr":  {Name: "github.com/gorhill/cronexpr", LicenceFile: "GPLv3"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/deps.json")
+			require.NoError(t, err)
+
+			got, err := SuggestOverrides(strings.NewReader(string(data)+deps), classifier, tc.overrides, 0)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestWriteSuggestions(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "overrides.fix.yaml")
+	licenceFile := "testdata/github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a/GPLv3"
+
+	suggestions := []Suggestion{
+		{
+			Module:     "github.com/gorhill/cronexpr",
+			Version:    "v0.0.0-20161205141322-d520615e531a",
+			Problem:    "no licence file found",
+			File:       licenceFile,
+			Candidates: []Candidate{{LicenceType: "GPL-3.0-only", Confidence: 1}, {LicenceType: "LGPL-3.0-only", Confidence: 0.9}},
+		},
+		{
+			Module:  "github.com/elastic/unknown",
+			Version: "v1.0.0",
+			Problem: "no licence file found",
+		},
+	}
+	require.NoError(t, WriteSuggestions(out, suggestions))
+
+	written, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Contains(t, string(written), "# Candidate licence types:\n#   GPL-3.0-only (confidence 1.00)\n#   LGPL-3.0-only (confidence 0.90)\n")
+	require.Contains(t, string(written), "# github.com/elastic/unknown@v1.0.0: no licence file found.\n# No licence matched.")
+
+	copied, err := os.ReadFile(filepath.Join(dir, "licences", "github.com", "gorhill", "cronexpr", "LICENCE"))
+	require.NoError(t, err)
+	original, err := os.ReadFile(licenceFile)
+	require.NoError(t, err)
+	require.Equal(t, original, copied)
+
+	// the suggestions are valid overrides
+	overrides, err := dependency.LoadOverrides(out)
+	require.NoError(t, err)
+	require.Len(t, overrides, 2)
+	require.Equal(t, "GPL-3.0-only", overrides["github.com/gorhill/cronexpr"].LicenceType)
+	require.Equal(t, filepath.Join(dir, "licences", "github.com", "gorhill", "cronexpr", "LICENCE"), overrides["github.com/gorhill/cronexpr"].LicenceFile)
+	require.Empty(t, overrides["github.com/elastic/unknown"].LicenceType)
+}
@@ -0,0 +1,214 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package detector
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"go.elastic.co/go-licence-detector/dependency"
+)
+
+// Kinds of problems found with overrides.
+const (
+	OverrideIssueUnused               = "unused"                 // the override does not apply to any dependency
+	OverrideIssueRedundantLicenceType = "redundant-licence-type" // the classifier detects the licence type of the override
+	OverrideIssueMissingFile          = "missing-file"           // the licence file of the override does not exist
+)
+
+// OverrideIssue is a problem found with an override by CheckOverrides.
+type OverrideIssue struct {
+	Override string `json:"override"` // key of the override
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+}
+
+// CheckOverrides checks the overrides against the dependencies read from data (output of go list -m -json all). It
+// reports the overrides that do not apply to any dependency, either because the module is absent or because a more
+// specific override applies, the overrides whose licence type is detected by the classifier without them, and the
+// licence files of overrides that do not exist. Overrides of modules that are in data but not downloaded are used, but
+// their licence types and files are not checked. Licence files are searched down to searchDepth levels, or
+// DefaultSearchDepth if searchDepth is not positive.
+func CheckOverrides(data io.Reader, classifier Classifier, overrides dependency.Overrides, searchDepth int) ([]OverrideIssue, error) {
+	deps, err := parseDependencies(data, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// overrides are used if they apply to any module of the input, even if the module is not downloaded
+	used := make(map[string]bool)
+	for _, mod := range deps.required {
+		if depInfo := mkDepInfo(mod, overrides); depInfo.Override != "" {
+			used[depInfo.Override] = true
+		}
+	}
+
+	if searchDepth <= 0 {
+		searchDepth = DefaultSearchDepth
+	}
+
+	licenceRegex := buildLicenceRegex()
+	redundant := make(map[string]bool)
+	var issues []OverrideIssue
+
+	// licence files can only be checked for the modules on disk
+	for _, mod := range slices.Concat(deps.direct, deps.indirect) {
+		depInfo := mkDepInfo(mod, overrides)
+		if depInfo.Override == "" {
+			continue
+		}
+
+		key := depInfo.Override
+
+		if depInfo.LicenceFile != "" && depInfo.LicenceTextOverrideFile == "" {
+			licFile, err := securejoin.SecureJoin(depInfo.Dir, depInfo.LicenceFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate secure path to licence file of %s: %w", depInfo.Name, err)
+			}
+
+			if ok, err := fileExists(licFile); err != nil {
+				return nil, err
+			} else if !ok {
+				issues = append(issues, OverrideIssue{
+					Override: key,
+					Kind:     OverrideIssueMissingFile,
+					Message:  fmt.Sprintf("licence file %s does not exist in %s", depInfo.LicenceFile, depInfo.Dir),
+				})
+				continue
+			}
+			depInfo.LicenceFile = licFile
+		}
+
+		if depInfo.LicenceType == "" {
+			continue
+		}
+
+		// a glob override is only redundant if the classifier detects its licence type for all matching modules
+		isRedundant, ok := redundant[key]
+		if ok && !isRedundant {
+			continue
+		}
+		redundant[key] = detectsLicenceType(licenceRegex, searchDepth, classifier, depInfo)
+	}
+
+	for key, override := range overrides {
+		if !used[key] {
+			issues = append(issues, OverrideIssue{
+				Override: key,
+				Kind:     OverrideIssueUnused,
+				Message:  "override does not apply to any dependency",
+			})
+			continue
+		}
+
+		if redundant[key] {
+			issues = append(issues, OverrideIssue{
+				Override: key,
+				Kind:     OverrideIssueRedundantLicenceType,
+				Message:  fmt.Sprintf("licence type %s is detected without the override", override.LicenceType),
+			})
+		}
+
+		if override.LicenceTextOverrideFile != "" {
+			if ok, err := fileExists(override.LicenceFile); err != nil {
+				return nil, err
+			} else if !ok {
+				issues = append(issues, OverrideIssue{
+					Override: key,
+					Kind:     OverrideIssueMissingFile,
+					Message:  fmt.Sprintf("licence text override file %s does not exist", override.LicenceTextOverrideFile),
+				})
+			}
+		}
+	}
+
+	slices.SortFunc(issues, func(a, b OverrideIssue) int {
+		return cmp.Or(cmp.Compare(a.Override, b.Override), cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Message, b.Message))
+	})
+
+	return issues, nil
+}
+
+// PruneOverrides returns a copy of the overrides without the unused overrides and the redundant licence types
+// reported by CheckOverrides. Overrides that are left without any information are removed. Missing licence files are
+// not pruned because they need to be fixed.
+func PruneOverrides(overrides dependency.Overrides, issues []OverrideIssue) dependency.Overrides {
+	pruned := make(dependency.Overrides, len(overrides))
+	for key, override := range overrides {
+		pruned[key] = override
+	}
+
+	for _, issue := range issues {
+		override, ok := pruned[issue.Override]
+		if !ok {
+			continue
+		}
+
+		switch issue.Kind {
+		case OverrideIssueUnused:
+			delete(pruned, issue.Override)
+		case OverrideIssueRedundantLicenceType:
+			override.LicenceType = ""
+			if override.IsEmptyOverride() {
+				delete(pruned, issue.Override)
+				continue
+			}
+			pruned[issue.Override] = override
+		}
+	}
+
+	return pruned
+}
+
+// detectsLicenceType returns true if the classifier detects the licence type of the override from the licence file
+// that would be used without it.
+func detectsLicenceType(licenceRegex *regexp.Regexp, searchDepth int, classifier Classifier, depInfo dependency.Info) bool {
+	if depInfo.LicenceText != "" {
+		detected, _, err := classifyLicenceText(classifier, "the licence text of the override", depInfo.LicenceText)
+		return err == nil && detected == depInfo.LicenceType
+	}
+
+	licenceFile := depInfo.LicenceFile
+	if licenceFile == "" {
+		var err error
+		if licenceFile, err = findLicenceFile(depInfo.Dir, licenceRegex, searchDepth); err != nil {
+			return false
+		}
+	}
+
+	detected, _, err := detectLicenceType(classifier, licenceFile)
+	return err == nil && detected == depInfo.LicenceType
+}
+
+func fileExists(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check licence file %s: %w", path, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,135 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package detector
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/go-licence-detector/dependency"
+)
+
+func TestCheckOverrides(t *testing.T) {
+	classifier, err := NewClassifier("")
+	require.NoError(t, err)
+
+	f, err := os.Open("testdata/deps.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	// modules that are not downloaded have no directory but are still part of the input
+	notDownloaded := strings.NewReader(`{"Path": "example.com/not-downloaded", "Version": "v1.2.0"}`)
+
+	overrides := dependency.Overrides{
+		"example.com/gone":                     {Name: "example.com/gone", LicenceType: "MIT"},
+		"example.com/not-downloaded@v1.2.0":    {Name: "example.com/not-downloaded@v1.2.0", LicenceType: "MIT"},
+		"example.com/not-downloaded@v1.1.0":    {Name: "example.com/not-downloaded@v1.1.0", LicenceType: "MIT"},
+		"github.com/davecgh/go-spew":           {Name: "github.com/davecgh/go-spew", LicenceType: "ISC"},
+		"github.com/dgryski/*":                 {Name: "github.com/dgryski/*", LicenceType: "MIT"},
+		"github.com/ekzhu/minhash-lsh":         {Name: "github.com/ekzhu/minhash-lsh", LicenceFile: "NOTICE"},
+		"github.com/elastic/test":              {Name: "github.com/elastic/test", LicenceFile: "/path/to/nowhere/LICENSE", LicenceTextOverrideFile: "LICENSE"},
+		"github.com/gorhill/cronexpr":          {Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0-only"},
+		"github.com/gorhill/cronexpr@v1.0.0":   {Name: "github.com/gorhill/cronexpr@v1.0.0", LicenceType: "GPL-3.0-only"},
+		"github.com/russross/blackfriday/v2":   {Name: "github.com/russross/blackfriday/v2", LicenceType: "BSD-2-Clause", URL: "https://gopkg.in/russross/blackfriday.v2"},
+		"github.com/russross/blackfriday/v2@*": {Name: "github.com/russross/blackfriday/v2@*", URL: "https://example.com/shadowed"},
+	}
+
+	issues, err := CheckOverrides(io.MultiReader(f, notDownloaded), classifier, overrides, 0)
+	require.NoError(t, err)
+
+	type issueKind struct{ override, kind string }
+	var got []issueKind
+	for _, issue := range issues {
+		require.NotEmpty(t, issue.Message)
+		got = append(got, issueKind{override: issue.Override, kind: issue.Kind})
+	}
+
+	require.Equal(t, []issueKind{
+		{override: "example.com/gone", kind: OverrideIssueUnused},
+		{override: "example.com/not-downloaded@v1.1.0", kind: OverrideIssueUnused},
+		{override: "github.com/davecgh/go-spew", kind: OverrideIssueRedundantLicenceType},
+		{override: "github.com/dgryski/*", kind: OverrideIssueRedundantLicenceType},
+		{override: "github.com/ekzhu/minhash-lsh", kind: OverrideIssueMissingFile},
+		{override: "github.com/elastic/test", kind: OverrideIssueMissingFile},
+		{override: "github.com/gorhill/cronexpr@v1.0.0", kind: OverrideIssueUnused},
+		{override: "github.com/russross/blackfriday/v2", kind: OverrideIssueRedundantLicenceType},
+		{override: "github.com/russross/blackfriday/v2@*", kind: OverrideIssueUnused},
+	}, got)
+
+	pruned := PruneOverrides(overrides, issues)
+	require.Equal(t, dependency.Overrides{
+		"example.com/not-downloaded@v1.2.0":  overrides["example.com/not-downloaded@v1.2.0"],
+		"github.com/ekzhu/minhash-lsh":       overrides["github.com/ekzhu/minhash-lsh"],
+		"github.com/elastic/test":            overrides["github.com/elastic/test"],
+		"github.com/gorhill/cronexpr":        overrides["github.com/gorhill/cronexpr"],
+		"github.com/russross/blackfriday/v2": {Name: "github.com/russross/blackfriday/v2", URL: "https://gopkg.in/russross/blackfriday.v2"},
+	}, pruned)
+	require.Len(t, overrides, 11, "the overrides must not be modified")
+}
+
+func TestCheckOverridesSearchDepth(t *testing.T) {
+	classifier, err := NewClassifier("")
+	require.NoError(t, err)
+
+	licence, err := os.ReadFile("testdata/github.com/dgryski/go-spooky@v0.0.0-20170606183049-ed3d087f40e2/COPYING")
+	require.NoError(t, err)
+
+	// the licence file is only found when the search goes four levels deep
+	dir := t.TempDir()
+	licenceFile := filepath.Join(dir, "a", "b", "c", "LICENSE")
+	require.NoError(t, os.MkdirAll(filepath.Dir(licenceFile), 0o755))
+	require.NoError(t, os.WriteFile(licenceFile, licence, 0o644))
+
+	data, err := json.Marshal(map[string]any{"Path": "example.com/deep", "Version": "v1.0.0", "Dir": dir})
+	require.NoError(t, err)
+
+	overrides := dependency.Overrides{
+		"example.com/deep": {Name: "example.com/deep", LicenceType: "MIT"},
+	}
+
+	testCases := []struct {
+		name        string
+		searchDepth int
+		want        []OverrideIssue
+	}{
+		{
+			name:        "DefaultDepth",
+			searchDepth: 0,
+		},
+		{
+			name:        "DeeperDepth",
+			searchDepth: 4,
+			want: []OverrideIssue{
+				{Override: "example.com/deep", Kind: OverrideIssueRedundantLicenceType, Message: "licence type MIT is detected without the override"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			issues, err := CheckOverrides(strings.NewReader(string(data)), classifier, overrides, tc.searchDepth)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, issues)
+		})
+	}
+}
@@ -20,62 +20,767 @@ package detector
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"go.elastic.co/go-licence-detector/assets"
+	"go.elastic.co/go-licence-detector/config"
+	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/licence"
+	"go.elastic.co/go-licence-detector/policy"
+	gomodule "golang.org/x/mod/module"
 )
 
+// exceptionDateFormat is the layout of the expiry date of licence exceptions.
+const exceptionDateFormat = "2006-01-02"
+
+// now returns the current time. It is a variable so that tests can control the expiry of exceptions.
+var now = time.Now
+
 // rulesFile represents the structure of the rules file.
 type rulesFile struct {
-	Allowlist []string `json:"allowlist"`
-	Maybelist []string `json:"maybelist"`
+	Allowlist          []string                        `json:"allowlist"`
+	Maybelist          []string                        `json:"maybelist"`
+	Denylist           []string                        `json:"denylist"`
+	AllowCategories    []string                        `json:"allowCategories"`
+	DenyCategories     []string                        `json:"denyCategories"`
+	RequireLicenceFile []string                        `json:"requireLicenceFile"`
+	Exceptions         map[string]dependency.Exception `json:"exceptions"` // keyed by module path
+	Contexts           map[string]contextRulesFile     `json:"contexts"`   // keyed by dependency context
+	Modules            []moduleRuleFile                `json:"modules"`
+	OutboundLicence    string                          `json:"outboundLicence"`
+	Policies           []policyFile                    `json:"policies"`
+	Profiles           map[string]profileFile          `json:"profiles"`
+}
+
+// profileFile represents the structure of a named profile in the rules file.
+type profileFile struct {
+	Extends string `json:"extends"` // name of the parent profile. Profiles extend the base rules if empty.
+	rulesFile
+}
+
+// policyFile represents the structure of a policy in the rules file.
+type policyFile struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"` // text/template executed with the dependency information
+}
+
+// moduleRuleFile represents the structure of a module rule in the rules file.
+type moduleRuleFile struct {
+	Path     string `json:"path"`     // module path glob
+	Versions string `json:"versions"` // version range. Empty matches all versions.
+	Action   string `json:"action"`   // allow or deny
+	Reason   string `json:"reason"`
+}
+
+// contextRulesFile represents the structure of the rules for a dependency context in the rules file.
+type contextRulesFile struct {
+	Allowlist       []string `json:"allowlist"`
+	Denylist        []string `json:"denylist"`
+	AllowCategories []string `json:"allowCategories"`
+	DenyCategories  []string `json:"denyCategories"`
 }
 
 // Rules holds rules for the detector.
+// Licence IDs listed in AllowList, Maybelist or Denylist take precedence over the category of the licence.
+// Licences in the Maybelist must be reviewed for each module using them and the reviews recorded in Approvals.
+// Exceptions grant a single module the use of a licence which is not otherwise allowed, unless it is in the Denylist.
+// Contexts hold additional rules for dependencies used in a specific context, which are evaluated before the other
+// rules. The Denylist applies to all contexts.
+// Modules allow or deny dependencies by module path and version regardless of their licence. They are evaluated
+// before all other rules.
+// OutboundLicence is the licence under which the project is distributed. If set, the licences of the dependencies
+// must be compatible with it.
+// Policies are evaluated for each dependency that satisfies the other rules and report their results on the dependency.
+// RequireLicenceFile holds the licences whose text carries the copyright notice of the module, so that a licence type
+// given by an override is not enough and the licence file of the module must be found or provided.
+// Profile is the name of the profile of the rules file that the rules were loaded from, if any.
 type Rules struct {
-	AllowList map[string]struct{}
-	Maybelist map[string]struct{}
+	Profile            string
+	AllowList          map[string]struct{}
+	Maybelist          map[string]struct{}
+	Denylist           map[string]struct{}
+	AllowCategories    map[licence.Category]struct{}
+	DenyCategories     map[licence.Category]struct{}
+	RequireLicenceFile map[string]struct{}
+	Exceptions         map[string]dependency.Exception
+	Contexts           map[string]*ContextRules
+	Modules            []ModuleRule
+	OutboundLicence    string
+	Policies           []*policy.Policy
+	Approvals          *Approvals
+}
+
+// Actions of module rules.
+const (
+	ModuleActionAllow = "allow"
+	ModuleActionDeny  = "deny"
+)
+
+// ModuleRule allows or denies the versions of the modules matching a path glob.
+// The glob follows the syntax of GOPRIVATE: it matches a module path if it matches a prefix of the path elements,
+// so that "go.elastic.co/*" matches "go.elastic.co/apm" as well as "go.elastic.co/apm/module/apmhttp".
+type ModuleRule struct {
+	Path     string
+	Versions dependency.VersionRange
+	Action   string
+	Reason   string
+}
+
+// Matches returns true if the rule applies to the given module version.
+func (mr ModuleRule) Matches(modulePath, version string) bool {
+	return gomodule.MatchPrefixPatterns(mr.Path, modulePath) && mr.Versions.Contains(version)
+}
+
+// ContextRules holds the rules for dependencies used in a specific context such as tests or tools.
+// Licence IDs take precedence over the category of the licence.
+type ContextRules struct {
+	AllowList       map[string]struct{}
+	Denylist        map[string]struct{}
+	AllowCategories map[licence.Category]struct{}
+	DenyCategories  map[licence.Category]struct{}
 }
 
 // LoadRules loads rules from the given path. Embedded rules file is loaded if the path is empty.
 func LoadRules(path string) (*Rules, error) {
-	var ruleBytes []byte
+	return LoadProfile(path, "")
+}
+
+// LoadProfile loads the rules of the named profile from the given path. Embedded rules file is loaded if the path is
+// empty and the base rules are loaded if the profile name is empty. Rules files can be written in JSON or YAML and
+// extend other rules files or directories of rules files (see layerRulesFiles).
+// A profile inherits the rules of its parent profile, or the base rules of the file if it does not extend another
+// profile. Lists are combined with those of the parent, while exceptions, policies and the outbound licence of the
+// profile replace those of the parent with the same key. Module rules of the profile are evaluated before the module
+// rules of the parent.
+func LoadProfile(path, profile string) (*Rules, error) {
+	rf, err := readRulesFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile != "" {
+		if rf, err = rf.resolveProfile(profile, nil); err != nil {
+			return nil, err
+		}
+	}
 
+	rules, err := mkRules(rf)
+	if err != nil {
+		if profile != "" {
+			return nil, fmt.Errorf("invalid profile %s: %w", profile, err)
+		}
+		return nil, err
+	}
+
+	rules.Profile = profile
+	return rules, nil
+}
+
+func readRulesFile(path string) (rulesFile, error) {
 	if path == "" {
-		ruleBytes = assets.Rules
-	} else {
+		var rf rulesFile
+		if err := json.Unmarshal(assets.Rules, &rf); err != nil {
+			return rulesFile{}, fmt.Errorf("failed to unmarshal rules: %w", err)
+		}
+		return rf, nil
+	}
+
+	docs, err := config.Load(path)
+	if err != nil {
+		return rulesFile{}, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	var rf rulesFile
+	for i, doc := range docs {
+		var layer rulesFile
+		if err := json.Unmarshal(doc.Data, &layer); err != nil {
+			return rulesFile{}, fmt.Errorf("failed to unmarshal rules from %s: %w", doc.Path, err)
+		}
+
+		if i == 0 {
+			rf = layer
+			continue
+		}
+		rf = layerRulesFiles(rf, layer)
+	}
+
+	return rf, nil
+}
+
+// layerRulesFiles layers the rules of a file on top of the rules of the file it extends. Lists and exceptions of both
+// files are combined, but denylisted licences and denied categories are removed from the allowed and maybelisted
+// licences and allowed categories of both files, so that the denylist always wins. Profiles of the file replace
+// the profiles of the base file with the same name.
+func layerRulesFiles(base, layer rulesFile) rulesFile {
+	merged := mergeRulesFiles(base, layer)
+	merged.Allowlist = slices.DeleteFunc(merged.Allowlist, func(l string) bool { return containsLicence(merged.Denylist, l) })
+	merged.Maybelist = slices.DeleteFunc(merged.Maybelist, func(l string) bool { return containsLicence(merged.Denylist, l) })
+	merged.AllowCategories = slices.DeleteFunc(merged.AllowCategories, func(c string) bool {
+		return slices.Contains(merged.DenyCategories, c)
+	})
+
+	for name, crf := range merged.Contexts {
+		crf.Allowlist = slices.DeleteFunc(crf.Allowlist, func(l string) bool {
+			return containsLicence(crf.Denylist, l) || containsLicence(merged.Denylist, l)
+		})
+		crf.AllowCategories = slices.DeleteFunc(crf.AllowCategories, func(c string) bool {
+			return slices.Contains(crf.DenyCategories, c) || slices.Contains(merged.DenyCategories, c)
+		})
+		merged.Contexts[name] = crf
+	}
+
+	if len(base.Profiles)+len(layer.Profiles) > 0 {
+		merged.Profiles = make(map[string]profileFile, len(base.Profiles)+len(layer.Profiles))
+		maps.Copy(merged.Profiles, base.Profiles)
+		maps.Copy(merged.Profiles, layer.Profiles)
+	}
+
+	return merged
+}
+
+// containsLicence returns true if the list contains the licence ID, comparing canonical IDs.
+func containsLicence(ids []string, id string) bool {
+	canonical := licence.Canonical(id)
+	return slices.ContainsFunc(ids, func(other string) bool { return licence.Canonical(other) == canonical })
+}
+
+func (rf rulesFile) resolveProfile(name string, seen []string) (rulesFile, error) {
+	if slices.Contains(seen, name) {
+		return rulesFile{}, fmt.Errorf("profile %s extends itself: %s", name, strings.Join(append(seen, name), " -> "))
+	}
+
+	pf, ok := rf.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(rf.Profiles))
+		for n := range rf.Profiles {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return rulesFile{}, fmt.Errorf("unknown profile %q. Available profiles are: %s", name, strings.Join(names, ", "))
+	}
+
+	if len(pf.Profiles) > 0 {
+		return rulesFile{}, fmt.Errorf("profile %s must not define profiles", name)
+	}
+
+	parent := rf
+	parent.Profiles = nil
+	if pf.Extends != "" {
 		var err error
-		ruleBytes, err = os.ReadFile(path)
+		if parent, err = rf.resolveProfile(pf.Extends, append(seen, name)); err != nil {
+			return rulesFile{}, err
+		}
+	}
+
+	return mergeRulesFiles(parent, pf.rulesFile), nil
+}
+
+func mergeRulesFiles(parent, child rulesFile) rulesFile {
+	merged := rulesFile{
+		Allowlist:          slices.Concat(parent.Allowlist, child.Allowlist),
+		Maybelist:          slices.Concat(parent.Maybelist, child.Maybelist),
+		Denylist:           slices.Concat(parent.Denylist, child.Denylist),
+		AllowCategories:    slices.Concat(parent.AllowCategories, child.AllowCategories),
+		DenyCategories:     slices.Concat(parent.DenyCategories, child.DenyCategories),
+		RequireLicenceFile: slices.Concat(parent.RequireLicenceFile, child.RequireLicenceFile),
+		Exceptions:         make(map[string]dependency.Exception, len(parent.Exceptions)+len(child.Exceptions)),
+		Contexts:           make(map[string]contextRulesFile, len(parent.Contexts)+len(child.Contexts)),
+		Modules:            slices.Concat(child.Modules, parent.Modules),
+		OutboundLicence:    coalesce(child.OutboundLicence, parent.OutboundLicence),
+	}
+
+	maps.Copy(merged.Exceptions, parent.Exceptions)
+	maps.Copy(merged.Exceptions, child.Exceptions)
+
+	maps.Copy(merged.Contexts, parent.Contexts)
+	for name, crf := range child.Contexts {
+		parentCRF := merged.Contexts[name]
+		merged.Contexts[name] = contextRulesFile{
+			Allowlist:       slices.Concat(parentCRF.Allowlist, crf.Allowlist),
+			Denylist:        slices.Concat(parentCRF.Denylist, crf.Denylist),
+			AllowCategories: slices.Concat(parentCRF.AllowCategories, crf.AllowCategories),
+			DenyCategories:  slices.Concat(parentCRF.DenyCategories, crf.DenyCategories),
+		}
+	}
+
+	for _, pf := range parent.Policies {
+		if i := slices.IndexFunc(child.Policies, func(other policyFile) bool { return other.Name == pf.Name }); i >= 0 {
+			pf = child.Policies[i]
+		}
+		merged.Policies = append(merged.Policies, pf)
+	}
+
+	for _, pf := range child.Policies {
+		if !slices.ContainsFunc(merged.Policies, func(other policyFile) bool { return other.Name == pf.Name }) {
+			merged.Policies = append(merged.Policies, pf)
+		}
+	}
+
+	return merged
+}
+
+func mkRules(rf rulesFile) (*Rules, error) {
+	rules := &Rules{}
+
+	var err error
+	if rules.AllowCategories, err = mkCategorySet(rf.AllowCategories); err != nil {
+		return nil, fmt.Errorf("invalid allowCategories in rules: %w", err)
+	}
+
+	if rules.DenyCategories, err = mkCategorySet(rf.DenyCategories); err != nil {
+		return nil, fmt.Errorf("invalid denyCategories in rules: %w", err)
+	}
+
+	if rules.Exceptions, err = mkExceptions(rf.Exceptions); err != nil {
+		return nil, fmt.Errorf("invalid exceptions in rules: %w", err)
+	}
+
+	if rules.Contexts, err = mkContextRules(rf.Contexts); err != nil {
+		return nil, fmt.Errorf("invalid contexts in rules: %w", err)
+	}
+
+	if rules.Modules, err = mkModuleRules(rf.Modules); err != nil {
+		return nil, fmt.Errorf("invalid modules in rules: %w", err)
+	}
+
+	if rf.OutboundLicence != "" && !licence.HasCompatibility(rf.OutboundLicence) {
+		return nil, fmt.Errorf("invalid outboundLicence in rules: no compatibility information for %s", rf.OutboundLicence)
+	}
+	rules.OutboundLicence = licence.Canonical(rf.OutboundLicence)
+
+	for i, pf := range rf.Policies {
+		p, err := policy.New(pf.Name, pf.Condition, pf.Severity, pf.Message)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read rules: %w", err)
+			return nil, fmt.Errorf("invalid policies in rules: %w", err)
+		}
+
+		if slices.ContainsFunc(rf.Policies[:i], func(other policyFile) bool { return other.Name == pf.Name }) {
+			return nil, fmt.Errorf("invalid policies in rules: duplicate policy %s", pf.Name)
 		}
 
+		rules.Policies = append(rules.Policies, p)
 	}
 
-	var rf rulesFile
-	if err := json.Unmarshal(ruleBytes, &rf); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal rules: %w", err)
+	if rules.AllowList, err = mkLicenceSet(rf.Allowlist); err != nil {
+		return nil, fmt.Errorf("invalid allowlist in rules: %w", err)
 	}
 
-	rules := &Rules{
-		AllowList: make(map[string]struct{}, len(rf.Allowlist)),
-		Maybelist: make(map[string]struct{}, len(rf.Maybelist)),
+	if rules.Maybelist, err = mkLicenceSet(rf.Maybelist); err != nil {
+		return nil, fmt.Errorf("invalid maybelist in rules: %w", err)
 	}
 
-	for _, w := range rf.Allowlist {
-		rules.AllowList[w] = struct{}{}
+	if rules.Denylist, err = mkLicenceSet(rf.Denylist); err != nil {
+		return nil, fmt.Errorf("invalid denylist in rules: %w", err)
 	}
 
-	for _, y := range rf.Maybelist {
-		rules.Maybelist[y] = struct{}{}
+	if rules.RequireLicenceFile, err = mkLicenceSet(rf.RequireLicenceFile); err != nil {
+		return nil, fmt.Errorf("invalid requireLicenceFile in rules: %w", err)
 	}
 
 	return rules, nil
 }
 
-// IsAllowed returns true if the given licence is allowed by the rules.
+func mkContextRules(entries map[string]contextRulesFile) (map[string]*ContextRules, error) {
+	contexts := make(map[string]*ContextRules, len(entries))
+	for name, crf := range entries {
+		if !slices.Contains(dependency.Contexts, name) {
+			return nil, fmt.Errorf("unknown dependency context %q. Valid contexts are: %s", name, strings.Join(dependency.Contexts, ", "))
+		}
+
+		cr := &ContextRules{}
+
+		var err error
+		if cr.AllowList, err = mkLicenceSet(crf.Allowlist); err != nil {
+			return nil, fmt.Errorf("invalid allowlist for %s dependencies: %w", name, err)
+		}
+
+		if cr.Denylist, err = mkLicenceSet(crf.Denylist); err != nil {
+			return nil, fmt.Errorf("invalid denylist for %s dependencies: %w", name, err)
+		}
+
+		if cr.AllowCategories, err = mkCategorySet(crf.AllowCategories); err != nil {
+			return nil, fmt.Errorf("invalid allowCategories for %s dependencies: %w", name, err)
+		}
+
+		if cr.DenyCategories, err = mkCategorySet(crf.DenyCategories); err != nil {
+			return nil, fmt.Errorf("invalid denyCategories for %s dependencies: %w", name, err)
+		}
+
+		contexts[name] = cr
+	}
+
+	return contexts, nil
+}
+
+func mkModuleRules(entries []moduleRuleFile) ([]ModuleRule, error) {
+	moduleRules := make([]ModuleRule, len(entries))
+	for i, mrf := range entries {
+		if mrf.Path == "" {
+			return nil, fmt.Errorf("module rule %d must specify the module path", i)
+		}
+
+		if strings.Contains(mrf.Path, ",") {
+			return nil, fmt.Errorf("module rule for %s must specify a single module path", mrf.Path)
+		}
+
+		if _, err := path.Match(mrf.Path, ""); err != nil {
+			return nil, fmt.Errorf("module rule for %s has an invalid module path: %w", mrf.Path, err)
+		}
+
+		if mrf.Action != ModuleActionAllow && mrf.Action != ModuleActionDeny {
+			return nil, fmt.Errorf("module rule for %s must specify the action %q or %q", mrf.Path, ModuleActionAllow, ModuleActionDeny)
+		}
+
+		versions, err := dependency.ParseVersionRange(mrf.Versions)
+		if err != nil {
+			return nil, fmt.Errorf("module rule for %s has an invalid version range: %w", mrf.Path, err)
+		}
+
+		moduleRules[i] = ModuleRule{Path: mrf.Path, Versions: versions, Action: mrf.Action, Reason: mrf.Reason}
+	}
+
+	return moduleRules, nil
+}
+
+// mkLicenceSet validates the licence IDs against the SPDX licence list and returns the set of their canonical IDs.
+func mkLicenceSet(ids []string) (map[string]struct{}, error) {
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		canonical, err := licence.NormaliseExpression(id)
+		if err != nil {
+			return nil, err
+		}
+		set[canonical] = struct{}{}
+	}
+
+	return set, nil
+}
+
+func mkCategorySet(names []string) (map[licence.Category]struct{}, error) {
+	categories := make(map[licence.Category]struct{}, len(names))
+	for _, n := range names {
+		c, err := licence.ParseCategory(n)
+		if err != nil {
+			return nil, err
+		}
+		categories[c] = struct{}{}
+	}
+
+	return categories, nil
+}
+
+func mkExceptions(entries map[string]dependency.Exception) (map[string]dependency.Exception, error) {
+	exceptions := make(map[string]dependency.Exception, len(entries))
+	for module, e := range entries {
+		e.Module = module
+
+		switch {
+		case e.Licence == "":
+			return nil, fmt.Errorf("exception for %s must specify the licence", module)
+		case e.Justification == "":
+			return nil, fmt.Errorf("exception for %s must specify a justification", module)
+		case e.Approver == "":
+			return nil, fmt.Errorf("exception for %s must specify the approver", module)
+		}
+
+		if _, err := time.Parse(exceptionDateFormat, e.Expires); err != nil {
+			return nil, fmt.Errorf("exception for %s must specify an expiry date in the format YYYY-MM-DD: %w", module, err)
+		}
+
+		canonical, err := licence.NormaliseExpression(e.Licence)
+		if err != nil {
+			return nil, fmt.Errorf("exception for %s has an invalid licence: %w", module, err)
+		}
+		e.Licence = canonical
+
+		exceptions[module] = e
+	}
+
+	return exceptions, nil
+}
+
+// Check evaluates the rules against the licence of the given dependency. It returns an error explaining why the
+// dependency is not acceptable. Otherwise, it records the exception or approval that allowed the dependency on
+// depInfo, or flags it as requiring a review if it uses a maybelisted licence that has not been approved. Licence
+// expressions are checked licence by licence.
+func (r *Rules) Check(depInfo *dependency.Info) error {
+	if mr := r.FindModuleRule(depInfo.Name, depInfo.Version); mr != nil {
+		if mr.Action == ModuleActionAllow {
+			return nil
+		}
+
+		if mr.Reason != "" {
+			return fmt.Errorf("dependency %s@%s is denied by the module rule for %s %s in the rules file: %s", depInfo.Name, depInfo.Version, mr.Path, mr.Versions, mr.Reason)
+		}
+		return fmt.Errorf("dependency %s@%s is denied by the module rule for %s %s in the rules file", depInfo.Name, depInfo.Version, mr.Path, mr.Versions)
+	}
+
+	// licence types that are not valid expressions, such as the licence types of old overrides, are checked as a
+	// single licence ID
+	expr, err := licence.ParseExpression(depInfo.LicenceType)
+	if err != nil || expr.IsSimple() {
+		expr = &licence.Expression{Licence: depInfo.LicenceType}
+	}
+
+	result := r.checkExpression(depInfo, expr)
+	if result.err != nil {
+		return result.err
+	}
+
+	if result.verdict == verdictReview {
+		depInfo.Approval = r.Approvals.Find(*depInfo)
+		depInfo.ReviewRequired = depInfo.Approval == nil
+	}
+	depInfo.Exception = result.exception
+
+	return nil
+}
+
+// licenceVerdict is the outcome of the rules for a licence, from the most to the least acceptable.
+type licenceVerdict int
+
+const (
+	verdictAllowed licenceVerdict = iota
+	verdictReview
+	verdictRejected
+)
+
+// ruleResult is the outcome of the rules for a licence expression.
+type ruleResult struct {
+	verdict   licenceVerdict
+	exception *dependency.Exception // exception allowing the licence, if any
+	err       error                 // reason of the rejection
+}
+
+// checkExpression evaluates the rules against each licence of the expression. One acceptable alternative of an OR
+// expression and all parts of an AND expression must be acceptable.
+func (r *Rules) checkExpression(depInfo *dependency.Info, expr *licence.Expression) ruleResult {
+	switch expr.Operator {
+	case licence.OperatorOr:
+		var best ruleResult
+		for i, op := range expr.Operands {
+			if result := r.checkExpression(depInfo, op); i == 0 || result.verdict < best.verdict {
+				best = result
+			}
+		}
+		return best
+	case licence.OperatorAnd:
+		worst := ruleResult{verdict: verdictAllowed}
+		for _, op := range expr.Operands {
+			result := r.checkExpression(depInfo, op)
+			switch {
+			case result.verdict > worst.verdict:
+				worst = result
+			case worst.exception == nil:
+				worst.exception = result.exception
+			}
+		}
+		return worst
+	}
+
+	return r.checkLicence(depInfo, expr.String())
+}
+
+// checkLicence evaluates the rules against a single licence of the dependency.
+func (r *Rules) checkLicence(depInfo *dependency.Info, licenceID string) ruleResult {
+	rejected := func(format string, args ...any) ruleResult {
+		return ruleResult{verdict: verdictRejected, err: fmt.Errorf(format, args...)}
+	}
+
+	if _, isDenyListed := r.Denylist[licence.Canonical(licenceID)]; isDenyListed {
+		return rejected("dependency %s uses licence %s which is denied by the rules file", depInfo.Name, licenceID)
+	}
+
+	if cr, ok := r.Contexts[depInfo.Context]; ok {
+		if cr.IsDenied(licenceID) {
+			return rejected("%s dependency %s uses licence %s which is denied for %s dependencies by the rules file", depInfo.Context, depInfo.Name, licenceID, depInfo.Context)
+		}
+
+		if cr.IsAllowed(licenceID) {
+			return ruleResult{verdict: verdictAllowed}
+		}
+	}
+
+	if r.NeedsReview(licenceID) {
+		return ruleResult{verdict: verdictReview}
+	}
+
+	if r.IsAllowed(licenceID) {
+		return ruleResult{verdict: verdictAllowed}
+	}
+
+	// an exception can be granted for a licence of the expression or for the whole expression
+	if e, ok := r.Exceptions[depInfo.Name]; ok && (e.Licence == licence.Canonical(licenceID) || e.Licence == licence.Canonical(depInfo.LicenceType)) {
+		// exceptions are valid until the end of the expiry day
+		expires, _ := time.Parse(exceptionDateFormat, e.Expires)
+		if !now().Before(expires.AddDate(0, 0, 1)) {
+			return rejected("exception for dependency %s to use licence %s (approved by %s) expired on %s. Renew or remove the exception to continue.", depInfo.Name, e.Licence, e.Approver, e.Expires)
+		}
+		return ruleResult{verdict: verdictAllowed, exception: &e}
+	}
+
+	if r.IsDenied(licenceID) {
+		return rejected("dependency %s uses %s licence %s which is denied by the rules file", depInfo.Name, licence.CategoryOf(licenceID), licenceID)
+	}
+
+	return rejected("dependency %s uses licence %s which is not allowed by the rules file", depInfo.Name, licenceID)
+}
+
+// CheckCompatibility returns an error explaining why the licence of the given dependency is incompatible with the
+// outbound licence. The compatibility of test and tool dependencies, which are not distributed with the project, is
+// not checked. Exceptions, approvals and module rules only allow the licence of a dependency, so they do not make it
+// compatible with the outbound licence.
+func (r *Rules) CheckCompatibility(outbound string, depInfo *dependency.Info) error {
+	if outbound == "" || !licence.HasCompatibility(outbound) {
+		return nil
+	}
+
+	if depInfo.Context == dependency.ContextTest || depInfo.Context == dependency.ContextTool {
+		return nil
+	}
+
+	expr, err := licence.ParseExpression(depInfo.LicenceType)
+	if err != nil {
+		return fmt.Errorf("failed to check compatibility of dependency %s: %w", depInfo.Name, err)
+	}
+
+	if err := licence.CheckCompatibility(outbound, expr); err != nil {
+		return fmt.Errorf("dependency %s uses licence %s which is incompatible with the outbound licence %s (%w)", depInfo.Name, depInfo.LicenceType, outbound, err)
+	}
+
+	return nil
+}
+
+// EvaluatePolicies returns the results of the policies matching the given dependency.
+func (r *Rules) EvaluatePolicies(depInfo *dependency.Info) ([]dependency.PolicyResult, error) {
+	var results []dependency.PolicyResult
+	for _, p := range r.Policies {
+		result, err := p.Evaluate(depInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results, nil
+}
+
+// FindModuleRule returns the first module rule that applies to the given module version, if any.
+func (r *Rules) FindModuleRule(modulePath, version string) *ModuleRule {
+	for i := range r.Modules {
+		if r.Modules[i].Matches(modulePath, version) {
+			return &r.Modules[i]
+		}
+	}
+
+	return nil
+}
+
+// IsAllowed returns true if the given licence is allowed by the rules without a review.
+// Denied licences are never allowed, even if they are in the allowlist as well.
 func (r *Rules) IsAllowed(licenceID string) bool {
+	licenceID = licence.Canonical(licenceID)
+
+	if r.IsDenied(licenceID) {
+		return false
+	}
+
+	if _, isAllowListed := r.AllowList[licenceID]; isAllowListed {
+		return true
+	}
+
+	if _, isMaybeListed := r.Maybelist[licenceID]; isMaybeListed {
+		return false
+	}
+
+	_, isCategoryAllowed := r.AllowCategories[licence.CategoryOf(licenceID)]
+	return isCategoryAllowed
+}
+
+// RequiresLicenceFile returns true if any licence of the licence expression requires the licence file of the module.
+func (r *Rules) RequiresLicenceFile(licenceType string) bool {
+	expr, err := licence.ParseExpression(licenceType)
+	if err != nil {
+		_, ok := r.RequireLicenceFile[licence.Canonical(licenceType)]
+		return ok
+	}
+
+	return slices.ContainsFunc(expr.Licences(), func(id string) bool {
+		_, ok := r.RequireLicenceFile[licence.Canonical(id)]
+		return ok
+	})
+}
+
+// NeedsReview returns true if the given licence is in the maybelist and must be approved for each module using it.
+func (r *Rules) NeedsReview(licenceID string) bool {
+	licenceID = licence.Canonical(licenceID)
+
+	if r.IsDenied(licenceID) {
+		return false
+	}
+
 	_, isAllowListed := r.AllowList[licenceID]
 	_, isMaybeListed := r.Maybelist[licenceID]
-	return isAllowListed || isMaybeListed
+	return isMaybeListed && !isAllowListed
+}
+
+// IsDenied returns true if the given licence is explicitly denied by the rules, either by ID or by category.
+// A licence in a denied category is not denied if its ID is in the allowlist or maybelist.
+func (r *Rules) IsDenied(licenceID string) bool {
+	licenceID = licence.Canonical(licenceID)
+
+	if _, isDenyListed := r.Denylist[licenceID]; isDenyListed {
+		return true
+	}
+
+	_, isAllowListed := r.AllowList[licenceID]
+	_, isMaybeListed := r.Maybelist[licenceID]
+	if isAllowListed || isMaybeListed {
+		return false
+	}
+
+	_, isCategoryDenied := r.DenyCategories[licence.CategoryOf(licenceID)]
+	return isCategoryDenied
+}
+
+// IsAllowed returns true if the given licence is allowed for dependencies in the context.
+func (cr *ContextRules) IsAllowed(licenceID string) bool {
+	licenceID = licence.Canonical(licenceID)
+
+	if cr.IsDenied(licenceID) {
+		return false
+	}
+
+	if _, isAllowListed := cr.AllowList[licenceID]; isAllowListed {
+		return true
+	}
+
+	_, isCategoryAllowed := cr.AllowCategories[licence.CategoryOf(licenceID)]
+	return isCategoryAllowed
+}
+
+// IsDenied returns true if the given licence is denied for dependencies in the context, either by ID or by category.
+// A licence in a denied category is not denied if its ID is in the allowlist of the context.
+func (cr *ContextRules) IsDenied(licenceID string) bool {
+	licenceID = licence.Canonical(licenceID)
+
+	if _, isDenyListed := cr.Denylist[licenceID]; isDenyListed {
+		return true
+	}
+
+	if _, isAllowListed := cr.AllowList[licenceID]; isAllowListed {
+		return false
+	}
+
+	_, isCategoryDenied := cr.DenyCategories[licence.CategoryOf(licenceID)]
+	return isCategoryDenied
 }
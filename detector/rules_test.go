@@ -18,10 +18,21 @@
 package detector
 
 import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/licence"
+	"go.elastic.co/go-licence-detector/policy"
 )
 
 func TestLoadRules(t *testing.T) {
@@ -42,6 +53,73 @@ func TestLoadRules(t *testing.T) {
 	})
 }
 
+func TestRequiresLicenceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"requireLicenceFile": ["mit", "BSD-3-Clause"]}`), 0o600))
+
+	rules, err := LoadRules(path)
+	require.NoError(t, err)
+
+	require.True(t, rules.RequiresLicenceFile("MIT"))
+	require.True(t, rules.RequiresLicenceFile("Apache-2.0 OR BSD-3-Clause"))
+	require.False(t, rules.RequiresLicenceFile("Apache-2.0"))
+	require.False(t, rules.RequiresLicenceFile("LicenseRef-Public-Domain"))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"requireLicenceFile": ["MTI"]}`), 0o600))
+	_, err = LoadRules(path)
+	require.ErrorContains(t, err, `invalid requireLicenceFile in rules: unknown licence ID "MTI". Did you mean "MIT"?`)
+}
+
+func TestLoadRulesLicenceIDs(t *testing.T) {
+	t.Run("normalised", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"allowlist": ["gpl-2.0", "Public Domain"], "denylist": ["AGPL-3.0"]}`), 0o600))
+
+		rules, err := LoadRules(path)
+		require.NoError(t, err)
+		require.Contains(t, rules.AllowList, "GPL-2.0-only")
+		require.Contains(t, rules.AllowList, "LicenseRef-Public-Domain")
+		require.Contains(t, rules.Denylist, "AGPL-3.0-only")
+
+		require.True(t, rules.IsAllowed("GPL-2.0"))
+		require.True(t, rules.IsAllowed("GPL-2.0-only"))
+		require.True(t, rules.IsAllowed("Public Domain"))
+		require.True(t, rules.IsDenied("AGPL-3.0-only"))
+	})
+
+	testCases := map[string]struct {
+		rules   string
+		wantErr string
+	}{
+		"Allowlist": {
+			rules:   `{"allowlist": ["Apache 2.0"]}`,
+			wantErr: `invalid allowlist in rules: unknown licence ID "Apache 2.0". Did you mean "Apache-2.0"?`,
+		},
+		"Denylist": {
+			rules:   `{"denylist": ["BSD-3"]}`,
+			wantErr: `invalid denylist in rules: unknown licence ID "BSD-3". Did you mean "BSD-3-Clause"?`,
+		},
+		"Context": {
+			rules:   `{"contexts": {"test": {"allowlist": ["Internal Licence"]}}}`,
+			wantErr: `unknown licence ID "Internal Licence". Use the LicenseRef- prefix`,
+		},
+		"Exception": {
+			rules:   `{"exceptions": {"example.com/a": {"licence": "GLP-3.0", "justification": "j", "approver": "a", "expires": "2030-01-01"}}}`,
+			wantErr: `exception for example.com/a has an invalid licence: unknown licence ID "GLP-3.0". Did you mean "GPL-3.0-only"?`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.rules), 0o600))
+
+			_, err := LoadRules(path)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
 func TestRulesAllowList(t *testing.T) {
 	rules, err := LoadRules("testdata/rules.json")
 
@@ -54,6 +132,782 @@ func TestRulesMaybeList(t *testing.T) {
 	rules, err := LoadRules("testdata/rules.json")
 
 	require.NoError(t, err)
-	require.True(t, rules.IsAllowed("GPL-3.0"))
+	// maybelisted licences are not allowed without a review
+	require.False(t, rules.IsAllowed("GPL-3.0"))
+	require.True(t, rules.NeedsReview("GPL-3.0"))
+	require.False(t, rules.IsDenied("GPL-3.0"))
 	require.False(t, rules.IsAllowed("WTFPL"))
+	require.False(t, rules.NeedsReview("WTFPL"))
+}
+
+func TestRulesCheckReviews(t *testing.T) {
+	rules, err := LoadRules("testdata/rules.json")
+	require.NoError(t, err)
+
+	rules.Approvals, err = LoadApprovals("testdata/approvals.json")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		depInfo            dependency.Info
+		wantApproval       bool
+		wantReviewRequired bool
+	}{
+		{
+			name:         "Approved",
+			depInfo:      dependency.Info{Name: "github.com/gorhill/cronexpr", Version: "v0.0.0-20161205141322-d520615e531a", LicenceType: "GPL-3.0"},
+			wantApproval: true,
+		},
+		{
+			name:               "VersionNotApproved",
+			depInfo:            dependency.Info{Name: "github.com/gorhill/cronexpr", Version: "v1.0.0", LicenceType: "GPL-3.0"},
+			wantReviewRequired: true,
+		},
+		{
+			name:               "ModuleNotApproved",
+			depInfo:            dependency.Info{Name: "github.com/elastic/test", Version: "v0.0.1", LicenceType: "GPL-3.0"},
+			wantReviewRequired: true,
+		},
+		{
+			name:    "NotMaybelisted",
+			depInfo: dependency.Info{Name: "github.com/gorhill/cronexpr", Version: "v0.0.0-20161205141322-d520615e531a", LicenceType: "MIT"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depInfo := tc.depInfo
+			require.NoError(t, rules.Check(&depInfo))
+			require.Equal(t, tc.wantApproval, depInfo.Approval != nil)
+			require.Equal(t, tc.wantReviewRequired, depInfo.ReviewRequired)
+		})
+	}
+}
+
+func TestLoadApprovalsInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"MissingModule":   `{"approvals": [{"licence": "GPL-3.0", "reviewer": "r", "date": "2020-01-01"}]}`,
+		"MissingLicence":  `{"approvals": [{"module": "example.com/a", "reviewer": "r", "date": "2020-01-01"}]}`,
+		"MissingReviewer": `{"approvals": [{"module": "example.com/a", "licence": "GPL-3.0", "date": "2020-01-01"}]}`,
+		"InvalidDate":     `{"approvals": [{"module": "example.com/a", "licence": "GPL-3.0", "reviewer": "r", "date": "yesterday"}]}`,
+		"InvalidVersions": `{"approvals": [{"module": "example.com/a", "versions": ">= latest", "licence": "GPL-3.0", "reviewer": "r", "date": "2020-01-01"}]}`,
+	}
+
+	for name, approvals := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "approvals.json")
+			require.NoError(t, os.WriteFile(path, []byte(approvals), 0o600))
+
+			_, err := LoadApprovals(path)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRulesDenyList(t *testing.T) {
+	rules, err := LoadRules("testdata/rules-denylist.json")
+
+	require.NoError(t, err)
+	require.True(t, rules.IsDenied("Elastic-2.0"))
+	require.False(t, rules.IsAllowed("Elastic-2.0"))
+	// denylist takes precedence over the allowlist
+	require.True(t, rules.IsDenied("MPL-2.0"))
+	require.False(t, rules.IsAllowed("MPL-2.0"))
+	require.False(t, rules.IsDenied("WTFPL"))
+}
+
+func TestEmbeddedRulesMaybeListSourceAvailable(t *testing.T) {
+	rules, err := LoadRules("")
+
+	require.NoError(t, err)
+	for _, id := range []string{"BUSL-1.1", "Elastic-2.0", "SSPL-1.0"} {
+		require.Contains(t, rules.Maybelist, id)
+		require.NotContains(t, rules.AllowList, id)
+	}
+}
+
+func TestRulesCategories(t *testing.T) {
+	rules, err := LoadRules("testdata/rules-categories.json")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		licenceID   string
+		wantAllowed bool
+		wantDenied  bool
+	}{
+		{licenceID: "MIT", wantAllowed: true},
+		{licenceID: "0BSD", wantAllowed: true},
+		{licenceID: "Unlicense", wantAllowed: true},
+		// ID rules take precedence over category rules
+		{licenceID: "LGPL-2.1", wantAllowed: true},
+		{licenceID: "MPL-2.0", wantDenied: true},
+		{licenceID: "SSPL-1.0", wantDenied: true},
+		// neither allowed nor denied
+		{licenceID: "GPL-3.0"},
+		{licenceID: "Totally Legit License 2.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.licenceID, func(t *testing.T) {
+			require.Equal(t, tc.wantAllowed, rules.IsAllowed(tc.licenceID))
+			require.Equal(t, tc.wantDenied, rules.IsDenied(tc.licenceID))
+		})
+	}
+}
+
+func TestRulesCheckExpressions(t *testing.T) {
+	rules, err := LoadRules("testdata/rules-categories.json")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		depInfo dependency.Info
+		wantErr string
+	}{
+		{
+			name:    "OrOneAllowed",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "MIT OR Elastic-2.0"},
+		},
+		{
+			name:    "OrNoneAllowed",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "Elastic-2.0 OR MPL-2.0"},
+			wantErr: "dependency example.com/a uses source-available licence Elastic-2.0 which is denied by the rules file",
+		},
+		{
+			name:    "AndAllAllowed",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "MIT AND LGPL-2.1"},
+		},
+		{
+			name:    "AndOneDenied",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "MIT AND (Elastic-2.0 OR MPL-2.0)"},
+			wantErr: "dependency example.com/a uses source-available licence Elastic-2.0 which is denied by the rules file",
+		},
+		{
+			name:    "AndOneUnknown",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "MIT AND Totally Legit License 2.0"},
+			wantErr: "dependency example.com/a uses licence Totally Legit License 2.0 which is not allowed by the rules file",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depInfo := tc.depInfo
+			err := rules.Check(&depInfo)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRulesCheckExpressionReviewsAndExceptions(t *testing.T) {
+	rules, err := LoadRules("testdata/rules-denylist.json")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name               string
+		depInfo            dependency.Info
+		wantReviewRequired bool
+		wantException      string
+		wantErr            string
+	}{
+		{
+			name:    "OrAllowedBeatsReview",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "GPL-3.0 OR MIT"},
+		},
+		{
+			name:    "OrAllowedBeatsDenied",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "MIT OR Elastic-2.0"},
+		},
+		{
+			name:    "OrNoneAllowed",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "Elastic-2.0 OR MPL-2.0"},
+			wantErr: "dependency example.com/a uses licence Elastic-2.0 which is denied by the rules file",
+		},
+		{
+			name:               "AndNeedsReview",
+			depInfo:            dependency.Info{Name: "example.com/a", LicenceType: "MIT AND GPL-3.0"},
+			wantReviewRequired: true,
+		},
+		{
+			name:          "OperandException",
+			depInfo:       dependency.Info{Name: "github.com/gorhill/cronexpr", LicenceType: "MIT AND AGPL-3.0"},
+			wantException: "AGPL-3.0-only",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depInfo := tc.depInfo
+			err := rules.Check(&depInfo)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.wantReviewRequired, depInfo.ReviewRequired)
+			if tc.wantException == "" {
+				require.Nil(t, depInfo.Exception)
+				return
+			}
+
+			require.NotNil(t, depInfo.Exception)
+			require.Equal(t, tc.wantException, depInfo.Exception.Licence)
+		})
+	}
+}
+
+func TestLoadRulesInvalidCategory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"allowCategories": ["copyleft-ish"]}`), 0o600))
+
+	_, err := LoadRules(path)
+	require.Error(t, err)
+}
+
+func TestLoadRulesInvalidContext(t *testing.T) {
+	testCases := map[string]string{
+		"UnknownContext":  `{"contexts": {"benchmark": {"allowlist": ["GPL-3.0"]}}}`,
+		"InvalidCategory": `{"contexts": {"test": {"allowCategories": ["copyleft-ish"]}}}`,
+	}
+
+	for name, rules := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(rules), 0o600))
+
+			_, err := LoadRules(path)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRulesCheckContexts(t *testing.T) {
+	rules, err := LoadRules("testdata/rules-contexts.json")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		depInfo dependency.Info
+		wantErr bool
+	}{
+		{
+			name:    "CategoryAllowedForTest",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "AGPL-3.0", Context: dependency.ContextTest},
+		},
+		{
+			name:    "CategoryNotAllowedForDirect",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "AGPL-3.0", Context: dependency.ContextDirect},
+			wantErr: true,
+		},
+		{
+			name:    "GlobalDenylistAppliesToTest",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "Elastic-2.0", Context: dependency.ContextTest},
+			wantErr: true,
+		},
+		{
+			name:    "DeniedForTool",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "BSD-2-Clause", Context: dependency.ContextTool},
+			wantErr: true,
+		},
+		{
+			name:    "AllowedForIndirect",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "BSD-2-Clause", Context: dependency.ContextIndirect},
+		},
+		{
+			name:    "FallbackToGlobalRules",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "MIT", Context: dependency.ContextTool},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depInfo := tc.depInfo
+			err := rules.Check(&depInfo)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRulesCheckModules(t *testing.T) {
+	rules, err := LoadRules("testdata/rules-modules.json")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		depInfo dependency.Info
+		wantErr string
+	}{
+		{
+			name:    "VersionBeforeRelicensing",
+			depInfo: dependency.Info{Name: "github.com/hashicorp/terraform", Version: "v1.5.7", LicenceType: "MPL-2.0"},
+		},
+		{
+			name:    "VersionAfterRelicensing",
+			depInfo: dependency.Info{Name: "github.com/hashicorp/terraform", Version: "v1.6.0", LicenceType: "BUSL-1.1"},
+			wantErr: "Relicensed to BUSL-1.1 in v1.6.0.",
+		},
+		{
+			name:    "SubmoduleAfterRelicensing",
+			depInfo: dependency.Info{Name: "github.com/hashicorp/terraform/api", Version: "v1.6.2", LicenceType: "MPL-2.0"},
+			wantErr: "denied by the module rule",
+		},
+		{
+			name:    "SimilarPathNotMatched",
+			depInfo: dependency.Info{Name: "github.com/hashicorp/terraform-exec", Version: "v1.6.0", LicenceType: "MPL-2.0"},
+		},
+		{
+			name:    "FirstPartyNamespace",
+			depInfo: dependency.Info{Name: "go.elastic.co/apm/module/apmhttp", Version: "v1.15.0", LicenceType: "Elastic-2.0"},
+		},
+		{
+			name:    "FirstPartyNamespaceBeforeDenylist",
+			depInfo: dependency.Info{Name: "github.com/elastic/go-elasticsearch", Version: "v8.0.0", LicenceType: "SSPL-1.0"},
+		},
+		{
+			name:    "NoModuleRule",
+			depInfo: dependency.Info{Name: "example.com/a", Version: "v1.0.0", LicenceType: "Elastic-2.0"},
+			wantErr: "not allowed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depInfo := tc.depInfo
+			err := rules.Check(&depInfo)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLoadRulesInvalidModule(t *testing.T) {
+	testCases := map[string]string{
+		"MissingPath":     `{"modules": [{"action": "allow"}]}`,
+		"InvalidPath":     `{"modules": [{"path": "github.com/[elastic", "action": "allow"}]}`,
+		"MultiplePaths":   `{"modules": [{"path": "go.elastic.co/*,github.com/elastic/*", "action": "allow"}]}`,
+		"InvalidAction":   `{"modules": [{"path": "go.elastic.co/*", "action": "approve"}]}`,
+		"InvalidVersions": `{"modules": [{"path": "go.elastic.co/*", "versions": ">= latest", "action": "deny"}]}`,
+	}
+
+	for name, rules := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(rules), 0o600))
+
+			_, err := LoadRules(path)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRulesCheckCompatibility(t *testing.T) {
+	rules, err := LoadRules("testdata/rules-modules.json")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		outbound string
+		depInfo  dependency.Info
+		wantErr  bool
+	}{
+		{
+			name:     "Compatible",
+			outbound: "Apache-2.0",
+			depInfo:  dependency.Info{Name: "example.com/a", LicenceType: "MIT", Context: dependency.ContextDirect},
+		},
+		{
+			name:     "Incompatible",
+			outbound: "Apache-2.0",
+			depInfo:  dependency.Info{Name: "example.com/a", LicenceType: "GPL-2.0", Context: dependency.ContextDirect},
+			wantErr:  true,
+		},
+		{
+			name:     "IncompatibleExpression",
+			outbound: "Apache-2.0",
+			depInfo:  dependency.Info{Name: "example.com/a", LicenceType: "MIT AND GPL-2.0", Context: dependency.ContextIndirect},
+			wantErr:  true,
+		},
+		{
+			name:     "TestDependency",
+			outbound: "Apache-2.0",
+			depInfo:  dependency.Info{Name: "example.com/a", LicenceType: "GPL-2.0", Context: dependency.ContextTest},
+		},
+		{
+			name:     "ToolDependency",
+			outbound: "Apache-2.0",
+			depInfo:  dependency.Info{Name: "example.com/a", LicenceType: "GPL-2.0", Context: dependency.ContextTool},
+		},
+		{
+			name:     "AllowedModule",
+			outbound: "Apache-2.0",
+			depInfo:  dependency.Info{Name: "go.elastic.co/a", LicenceType: "GPL-2.0", Context: dependency.ContextDirect},
+			wantErr:  true,
+		},
+		{
+			name:     "Exception",
+			outbound: "Apache-2.0",
+			depInfo:  dependency.Info{Name: "example.com/a", LicenceType: "GPL-2.0", Context: dependency.ContextDirect, Exception: &dependency.Exception{}},
+			wantErr:  true,
+		},
+		{
+			name:     "Approval",
+			outbound: "Apache-2.0",
+			depInfo:  dependency.Info{Name: "example.com/a", LicenceType: "GPL-2.0", Context: dependency.ContextDirect, Approval: &dependency.Approval{}},
+			wantErr:  true,
+		},
+		{
+			name:    "NoOutboundLicence",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "GPL-2.0", Context: dependency.ContextDirect},
+		},
+		{
+			name:     "UnknownOutboundLicence",
+			outbound: "WTFPL",
+			depInfo:  dependency.Info{Name: "example.com/a", LicenceType: "GPL-2.0", Context: dependency.ContextDirect},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depInfo := tc.depInfo
+			err := rules.CheckCompatibility(tc.outbound, &depInfo)
+			if tc.wantErr {
+				require.ErrorContains(t, err, "incompatible with the outbound licence")
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLoadRulesInvalidOutboundLicence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"outboundLicence": "WTFPL"}`), 0o600))
+
+	_, err := LoadRules(path)
+	require.Error(t, err)
+}
+
+func TestRulesEvaluatePolicies(t *testing.T) {
+	rules, err := LoadRules("testdata/rules-policies.json")
+	require.NoError(t, err)
+	require.Len(t, rules.Policies, 3)
+
+	testCases := []struct {
+		name    string
+		depInfo dependency.Info
+		want    []dependency.PolicyResult
+	}{
+		{
+			name:    "NoMatch",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "MIT", LicenceCategory: "permissive", LicenceSource: dependency.LicenceSourceFile, LicenceConfidence: 1, Context: dependency.ContextDirect},
+		},
+		{
+			name:    "UnmodifiedIndirect",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "MPL-2.0", LicenceCategory: "weak-copyleft", LicenceSource: dependency.LicenceSourceOverride, Context: dependency.ContextIndirect},
+			want: []dependency.PolicyResult{
+				{Policy: "unmodified-weak-copyleft", Severity: "info", Message: "example.com/a is an unmodified indirect dependency licensed under MPL-2.0."},
+			},
+		},
+		{
+			name:    "ModifiedWithLowConfidence",
+			depInfo: dependency.Info{Name: "example.com/a", LicenceType: "MPL-2.0", LicenceCategory: "weak-copyleft", LicenceSource: dependency.LicenceSourceFile, LicenceConfidence: 0.9, Context: dependency.ContextDirect, LocalReplacement: true},
+			want: []dependency.PolicyResult{
+				{Policy: "modified-weak-copyleft", Severity: "error", Message: "example.com/a is a modified copy of MPL-2.0 code. Publish the changes before shipping."},
+				{Policy: "low-confidence", Severity: "warn", Message: "Licence of example.com/a detected with low confidence."},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := rules.EvaluatePolicies(&tc.depInfo)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestLoadRulesInvalidPolicy(t *testing.T) {
+	testCases := map[string]string{
+		"InvalidCondition": `{"policies": [{"name": "p", "condition": "indirect &&", "severity": "warn"}]}`,
+		"InvalidSeverity":  `{"policies": [{"name": "p", "condition": "indirect", "severity": "fatal"}]}`,
+		"DuplicateName":    `{"policies": [{"name": "p", "condition": "indirect", "severity": "warn"}, {"name": "p", "condition": "direct", "severity": "info"}]}`,
+	}
+
+	for name, rules := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(rules), 0o600))
+
+			_, err := LoadRules(path)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestLoadRulesExtends(t *testing.T) {
+	rules, err := LoadRules("testdata/bundle/team.yaml")
+	require.NoError(t, err)
+
+	// the denylist of the organisation wins over the allowlist of the team
+	require.True(t, rules.IsAllowed("MIT"))
+	require.True(t, rules.IsAllowed("LGPL-2.1"))
+	require.False(t, rules.IsAllowed("SSPL-1.0"))
+	require.False(t, rules.IsAllowed("AGPL-3.0"))
+	require.NotContains(t, rules.AllowCategories, licence.NetworkCopyleft)
+
+	// exceptions of both files apply
+	require.Contains(t, rules.Exceptions, "github.com/example/legacy")
+	require.Contains(t, rules.Exceptions, "github.com/example/fork")
+
+	depInfo := dependency.Info{Name: "github.com/example/fork", LicenceType: "AGPL-3.0"}
+	require.NoError(t, rules.Check(&depInfo))
+	require.NotNil(t, depInfo.Exception)
+}
+
+func TestRulesSchema(t *testing.T) {
+	data, err := os.ReadFile("../schema/rules.schema.json")
+	require.NoError(t, err)
+
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	testCases := map[string]struct {
+		have []string
+		want any
+	}{
+		"ruleSet":      {have: slices.Concat(slices.Collect(maps.Keys(schema.Defs["ruleSet"].Properties)), []string{"profiles"}), want: rulesFile{}},
+		"profile":      {have: slices.Collect(maps.Keys(schema.Defs["profile"].Properties)), want: struct{ Extends string }{}},
+		"exception":    {have: append(slices.Collect(maps.Keys(schema.Defs["exception"].Properties)), "module"), want: dependency.Exception{}},
+		"contextRules": {have: slices.Collect(maps.Keys(schema.Defs["contextRules"].Properties)), want: contextRulesFile{}},
+		"moduleRule":   {have: slices.Collect(maps.Keys(schema.Defs["moduleRule"].Properties)), want: moduleRuleFile{}},
+		"policy":       {have: slices.Collect(maps.Keys(schema.Defs["policy"].Properties)), want: policyFile{}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.ElementsMatch(t, jsonFieldNames(tc.want), tc.have)
+		})
+	}
+
+	require.ElementsMatch(t, []string{"extends", "profiles"}, slices.Collect(maps.Keys(schema.Properties)))
+}
+
+func jsonFieldNames(v any) []string {
+	typ := reflect.TypeOf(v)
+	names := make([]string, 0, typ.NumField())
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name == "" {
+			name = strings.ToLower(typ.Field(i).Name)
+		}
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func TestLoadProfile(t *testing.T) {
+	testCases := []struct {
+		profile     string
+		allowed     []string
+		notAllowed  []string
+		outbound    string
+		policies    map[string]string
+		moduleRules int
+	}{
+		{
+			profile:    "",
+			allowed:    []string{"MIT", "Unlicense"},
+			notAllowed: []string{"LGPL-2.1", "GPL-3.0", "AGPL-3.0", "SSPL-1.0"},
+			policies:   map[string]string{"low-confidence": policy.SeverityWarn},
+		},
+		{
+			profile:    "distribution",
+			allowed:    []string{"MIT", "LGPL-2.1"},
+			notAllowed: []string{"GPL-3.0", "AGPL-3.0", "SSPL-1.0"},
+			outbound:   "Apache-2.0",
+			policies:   map[string]string{"low-confidence": policy.SeverityError},
+		},
+		{
+			profile:     "saas",
+			allowed:     []string{"MIT", "LGPL-2.1", "GPL-3.0"},
+			notAllowed:  []string{"AGPL-3.0", "SSPL-1.0"},
+			policies:    map[string]string{"low-confidence": policy.SeverityWarn},
+			moduleRules: 1,
+		},
+		{
+			profile:     "internal",
+			allowed:     []string{"MIT", "LGPL-2.1", "GPL-3.0", "AGPL-3.0"},
+			notAllowed:  []string{"SSPL-1.0"},
+			policies:    map[string]string{"low-confidence": policy.SeverityWarn},
+			moduleRules: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			rules, err := LoadProfile("testdata/rules-profiles.json", tc.profile)
+			require.NoError(t, err)
+			require.Equal(t, tc.profile, rules.Profile)
+			require.Equal(t, tc.outbound, rules.OutboundLicence)
+			require.Len(t, rules.Modules, tc.moduleRules)
+
+			for _, l := range tc.allowed {
+				require.True(t, rules.IsAllowed(l), l)
+			}
+
+			for _, l := range tc.notAllowed {
+				require.False(t, rules.IsAllowed(l), l)
+			}
+
+			have := make(map[string]string, len(rules.Policies))
+			for _, p := range rules.Policies {
+				have[p.Name] = p.Severity
+			}
+			require.Equal(t, tc.policies, have)
+		})
+	}
+}
+
+func TestLoadProfileInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"UnknownProfile": `{"profiles": {"other": {}}}`,
+		"UnknownParent":  `{"profiles": {"p": {"extends": "missing"}}}`,
+		"Cycle":          `{"profiles": {"p": {"extends": "q"}, "q": {"extends": "p"}}}`,
+		"NestedProfiles": `{"profiles": {"p": {"profiles": {"q": {}}}}}`,
+		"InvalidRules":   `{"profiles": {"p": {"allowCategories": ["unknown-category"]}}}`,
+	}
+
+	for name, rules := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(rules), 0o600))
+
+			_, err := LoadProfile(path, "p")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRulesCheckExceptions(t *testing.T) {
+	rules, err := LoadRules("testdata/rules-exceptions.json")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		now           string
+		depInfo       dependency.Info
+		wantException bool
+		wantErr       string
+	}{
+		{
+			name:    "Allowed",
+			depInfo: dependency.Info{Name: "example.com/valid", LicenceType: "MIT"},
+		},
+		{
+			name:          "ValidException",
+			depInfo:       dependency.Info{Name: "example.com/valid", LicenceType: "GPL-3.0"},
+			wantException: true,
+		},
+		{
+			name:          "ValidOnExpiryDay",
+			now:           "2030-06-30T23:59:59Z",
+			depInfo:       dependency.Info{Name: "example.com/valid", LicenceType: "GPL-3.0"},
+			wantException: true,
+		},
+		{
+			name:    "ExpiredAfterExpiryDay",
+			now:     "2030-07-01T00:00:00Z",
+			depInfo: dependency.Info{Name: "example.com/valid", LicenceType: "GPL-3.0"},
+			wantErr: "expired on 2030-06-30",
+		},
+		{
+			name:    "ExceptionForOtherLicence",
+			depInfo: dependency.Info{Name: "example.com/valid", LicenceType: "GPL-2.0"},
+			wantErr: "denied by the rules file",
+		},
+		{
+			name:    "ExceptionForOtherModule",
+			depInfo: dependency.Info{Name: "example.com/other", LicenceType: "GPL-3.0"},
+			wantErr: "denied by the rules file",
+		},
+		{
+			name:    "DenylistBeatsException",
+			depInfo: dependency.Info{Name: "example.com/denied", LicenceType: "AGPL-3.0"},
+			wantErr: "denied by the rules file",
+		},
+		{
+			name:    "Expired",
+			depInfo: dependency.Info{Name: "example.com/expired", LicenceType: "LGPL-3.0"},
+			wantErr: "expired on 2020-01-31",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.now != "" {
+				fixedNow, err := time.Parse(time.RFC3339, tc.now)
+				require.NoError(t, err)
+				now = func() time.Time { return fixedNow }
+				defer func() { now = time.Now }()
+			}
+
+			depInfo := tc.depInfo
+			err := rules.Check(&depInfo)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			if !tc.wantException {
+				require.Nil(t, depInfo.Exception)
+				return
+			}
+
+			require.NotNil(t, depInfo.Exception)
+			require.Equal(t, tc.depInfo.Name, depInfo.Exception.Module)
+			require.Equal(t, licence.Canonical(tc.depInfo.LicenceType), depInfo.Exception.Licence)
+		})
+	}
+}
+
+func TestLoadRulesInvalidException(t *testing.T) {
+	testCases := map[string]string{
+		"MissingLicence":       `{"exceptions": {"example.com/a": {"justification": "j", "approver": "a", "expires": "2030-01-01"}}}`,
+		"MissingJustification": `{"exceptions": {"example.com/a": {"licence": "GPL-3.0", "approver": "a", "expires": "2030-01-01"}}}`,
+		"MissingApprover":      `{"exceptions": {"example.com/a": {"licence": "GPL-3.0", "justification": "j", "expires": "2030-01-01"}}}`,
+		"InvalidExpiry":        `{"exceptions": {"example.com/a": {"licence": "GPL-3.0", "justification": "j", "approver": "a", "expires": "soon"}}}`,
+	}
+
+	for name, rules := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(rules), 0o600))
+
+			_, err := LoadRules(path)
+			require.Error(t, err)
+		})
+	}
 }
@@ -0,0 +1,75 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+
+	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/detector"
+)
+
+// runFix handles the fix subcommand, which writes suggested overrides for all the modules whose licence cannot be
+// detected.
+func runFix(args []string) {
+	fs := flag.NewFlagSet("fix", flag.ExitOnError)
+	inFlag := fs.String("in", "-", "Dependency list (output from go list -m -json all).")
+	licenceDataFlag := fs.String("licenceData", "", "Path to the licence database. Uses embedded database if empty.")
+	outFlag := fs.String("out", "overrides.fix.yaml", "Path to output the suggested overrides. Candidate licence files are copied to the licences directory next to it.")
+	searchDepthFlag := fs.Int("licenceSearchDepth", detector.DefaultSearchDepth, "Maximum depth of the directories searched for the licence file of a dependency, the module root being at depth 1.")
+	var overridesFlags stringsFlag
+	fs.Var(&overridesFlags, "overrides", "Path to the file containing override directives. Can be repeated or given as a comma-separated list, with later files overriding the fields set by earlier files.")
+	_ = fs.Parse(args)
+
+	depData, err := readInput(*inFlag)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *inFlag, err)
+	}
+
+	classifier, err := detector.NewClassifier(*licenceDataFlag)
+	if err != nil {
+		log.Fatalf("Failed to create licence classifier: %v", err)
+	}
+
+	overrides, err := dependency.LoadOverrides(overridesFlags...)
+	if err != nil {
+		log.Fatalf("Failed to load overrides: %v", err)
+	}
+
+	suggestions, err := detector.SuggestOverrides(bytes.NewReader(depData), classifier, overrides, *searchDepthFlag)
+	if err != nil {
+		log.Fatalf("Failed to suggest overrides: %v", err)
+	}
+
+	if len(suggestions) == 0 {
+		log.Printf("The licences of all modules are detected")
+		return
+	}
+
+	for _, s := range suggestions {
+		log.Printf("%s@%s: %s", s.Module, s.Version, s.Problem)
+	}
+
+	if err := detector.WriteSuggestions(*outFlag, suggestions); err != nil {
+		log.Fatalf("Failed to write suggested overrides: %v", err)
+	}
+
+	log.Printf("Wrote %d suggested overrides to %s. Review them and pass the file with -overrides after the other overrides files.", len(suggestions), *outFlag)
+}
@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package licence holds information about licence types that is independent of any particular dependency.
+package licence // import "go.elastic.co/go-licence-detector/licence"
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"go.elastic.co/go-licence-detector/assets"
+)
+
+// Category groups licences with similar obligations.
+type Category string
+
+const (
+	// Permissive licences only require attribution (e.g. MIT, Apache-2.0).
+	Permissive Category = "permissive"
+	// PublicDomain licences waive all rights (e.g. CC0-1.0, Unlicense).
+	PublicDomain Category = "public-domain"
+	// WeakCopyleft licences require modifications of the licensed files to be shared under the same licence (e.g. MPL-2.0).
+	WeakCopyleft Category = "weak-copyleft"
+	// StrongCopyleft licences extend their terms to derived works (e.g. GPL-3.0).
+	StrongCopyleft Category = "strong-copyleft"
+	// NetworkCopyleft licences extend their terms to software accessed over a network (e.g. AGPL-3.0).
+	NetworkCopyleft Category = "network-copyleft"
+	// SourceAvailable licences make the source code available but restrict how it may be used (e.g. Elastic-2.0).
+	SourceAvailable Category = "source-available"
+	// Proprietary licences restrict use in ways incompatible with open source (e.g. non-commercial licences).
+	Proprietary Category = "proprietary"
+	// Unknown is the category of licences that are not in the embedded category mapping.
+	Unknown Category = "unknown"
+)
+
+// Categories lists all known licence categories.
+var Categories = []Category{Permissive, PublicDomain, WeakCopyleft, StrongCopyleft, NetworkCopyleft, SourceAvailable, Proprietary, Unknown}
+
+// categories maps licence IDs to their category.
+var categories = mustLoadCategories(assets.Categories)
+
+func mustLoadCategories(data []byte) map[string]Category {
+	var byCategory map[Category][]string
+	if err := json.Unmarshal(data, &byCategory); err != nil {
+		panic(fmt.Errorf("failed to unmarshal licence categories: %w", err))
+	}
+
+	byLicence := make(map[string]Category)
+	for category, licences := range byCategory {
+		if _, err := ParseCategory(string(category)); err != nil {
+			panic(err)
+		}
+
+		for _, l := range licences {
+			byLicence[Canonical(l)] = category
+		}
+	}
+
+	return byLicence
+}
+
+// CategoryOf returns the category of the given licence ID. Deprecated and alias IDs have the category of their
+// canonical ID.
+func CategoryOf(licenceID string) Category {
+	if category, ok := categories[Canonical(licenceID)]; ok {
+		return category
+	}
+
+	return Unknown
+}
+
+// CategoryOfExpression returns the category of the licence expression. The category of an OR expression is the
+// least restrictive category of its alternatives and the category of an AND expression is the most restrictive
+// category of its parts, categories being ordered from the least to the most restrictive in Categories. Licence types
+// that are not valid expressions are looked up as licence IDs.
+func CategoryOfExpression(licenceType string) Category {
+	expr, err := ParseExpression(licenceType)
+	if err != nil || expr.IsSimple() {
+		return CategoryOf(licenceType)
+	}
+
+	return expr.category()
+}
+
+func (e *Expression) category() Category {
+	if e.IsSimple() {
+		return CategoryOf(e.Licence)
+	}
+
+	rank := func(c Category) int { return slices.Index(Categories, c) }
+	category := e.Operands[0].category()
+	for _, op := range e.Operands[1:] {
+		c := op.category()
+		if (e.Operator == OperatorOr && rank(c) < rank(category)) || (e.Operator == OperatorAnd && rank(c) > rank(category)) {
+			category = c
+		}
+	}
+
+	return category
+}
+
+// ParseCategory returns the category with the given name.
+func ParseCategory(name string) (Category, error) {
+	for _, c := range Categories {
+		if string(c) == name {
+			return c, nil
+		}
+	}
+
+	return Unknown, fmt.Errorf("unknown licence category %q", name)
+}
@@ -0,0 +1,89 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryOf(t *testing.T) {
+	testCases := []struct {
+		licenceID string
+		want      Category
+	}{
+		{licenceID: "MIT", want: Permissive},
+		{licenceID: "BSD-2-Clause-Patent", want: Permissive},
+		{licenceID: "0BSD", want: Permissive},
+		{licenceID: "CC0-1.0", want: PublicDomain},
+		{licenceID: "Public Domain", want: PublicDomain},
+		{licenceID: "MPL-2.0", want: WeakCopyleft},
+		{licenceID: "LPL-1.0", want: WeakCopyleft},
+		{licenceID: "LPL-1.02", want: WeakCopyleft},
+		{licenceID: "EPL-2.0", want: WeakCopyleft},
+		{licenceID: "GPL-3.0", want: StrongCopyleft},
+		{licenceID: "AGPL-3.0", want: NetworkCopyleft},
+		{licenceID: "LGPL-3.0-or-later", want: WeakCopyleft},
+		{licenceID: "GPL-2.0-only", want: StrongCopyleft},
+		{licenceID: "CC-BY-NC-4.0", want: Proprietary},
+		{licenceID: "CC-BY-NC-ND-4.0", want: Proprietary},
+		{licenceID: "CC-BY-ND-3.0", want: SourceAvailable},
+		{licenceID: "CC-BY-ND-4.0", want: SourceAvailable},
+		{licenceID: "Elastic-2.0", want: SourceAvailable},
+		{licenceID: "SSPL-1.0", want: SourceAvailable},
+		{licenceID: "BUSL-1.1", want: SourceAvailable},
+		{licenceID: "Totally Legit License 2.0", want: Unknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.licenceID, func(t *testing.T) {
+			require.Equal(t, tc.want, CategoryOf(tc.licenceID))
+		})
+	}
+}
+
+func TestCategoryOfExpression(t *testing.T) {
+	testCases := []struct {
+		licenceType string
+		want        Category
+	}{
+		{licenceType: "MIT", want: Permissive},
+		{licenceType: "Totally Legit License 2.0", want: Unknown},
+		{licenceType: "MIT OR GPL-3.0", want: Permissive},
+		{licenceType: "MIT AND GPL-3.0", want: StrongCopyleft},
+		{licenceType: "MPL-2.0 AND (AGPL-3.0 OR Apache-2.0)", want: WeakCopyleft},
+		{licenceType: "MIT AND Totally Legit License 2.0", want: Unknown},
+		{licenceType: "MIT AND (", want: Unknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.licenceType, func(t *testing.T) {
+			require.Equal(t, tc.want, CategoryOfExpression(tc.licenceType))
+		})
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	c, err := ParseCategory("weak-copyleft")
+	require.NoError(t, err)
+	require.Equal(t, WeakCopyleft, c)
+
+	_, err = ParseCategory("copyleft-ish")
+	require.Error(t, err)
+}
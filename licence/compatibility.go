@@ -0,0 +1,173 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licence
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.elastic.co/go-licence-detector/assets"
+)
+
+// compatibilityFile represents the structure of the embedded compatibility matrix.
+type compatibilityFile struct {
+	CategoryReasons map[Category]string `json:"categoryReasons"`
+	Outbound        []struct {
+		Licences     []string          `json:"licences"`
+		Categories   []Category        `json:"categories"`
+		Compatible   []string          `json:"compatible"`
+		Incompatible map[string]string `json:"incompatible"` // reasons keyed by licence ID
+	} `json:"outbound"`
+}
+
+// compatibility describes which licences can be used by a project distributed under an outbound licence.
+// Licence IDs listed as compatible or incompatible take precedence over the category of the licence.
+type compatibility struct {
+	categories   map[Category]struct{}
+	compatible   map[string]struct{}
+	incompatible map[string]string
+}
+
+// compatibilityMatrix maps outbound licence IDs to the licences they are compatible with.
+var compatibilityMatrix, categoryReasons = mustLoadCompatibility(assets.Compatibility)
+
+func mustLoadCompatibility(data []byte) (map[string]*compatibility, map[Category]string) {
+	var cf compatibilityFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		panic(fmt.Errorf("failed to unmarshal licence compatibility matrix: %w", err))
+	}
+
+	matrix := make(map[string]*compatibility)
+	for _, entry := range cf.Outbound {
+		c := &compatibility{
+			categories:   make(map[Category]struct{}, len(entry.Categories)),
+			compatible:   make(map[string]struct{}, len(entry.Compatible)),
+			incompatible: make(map[string]string, len(entry.Incompatible)),
+		}
+
+		for l, reason := range entry.Incompatible {
+			c.incompatible[Canonical(l)] = reason
+		}
+
+		for _, category := range entry.Categories {
+			if _, err := ParseCategory(string(category)); err != nil {
+				panic(err)
+			}
+			c.categories[category] = struct{}{}
+		}
+
+		for _, l := range entry.Compatible {
+			c.compatible[Canonical(l)] = struct{}{}
+		}
+
+		for _, l := range entry.Licences {
+			matrix[Canonical(l)] = c
+		}
+	}
+
+	return matrix, cf.CategoryReasons
+}
+
+// HasCompatibility returns true if the embedded compatibility matrix has an entry for the given outbound licence.
+func HasCompatibility(outbound string) bool {
+	_, ok := compatibilityMatrix[canonicalOrLater(outbound)]
+	return ok
+}
+
+// CheckCompatibility returns an error explaining why the inbound licence expression cannot be used by a project
+// distributed under the outbound licence. At least one of the operands of an OR expression and all operands of an
+// AND expression must be compatible.
+func CheckCompatibility(outbound string, inbound *Expression) error {
+	outbound = canonicalOrLater(outbound)
+	c, ok := compatibilityMatrix[outbound]
+	if !ok {
+		return fmt.Errorf("no compatibility information for outbound licence %s", outbound)
+	}
+
+	return c.check(outbound, inbound)
+}
+
+func (c *compatibility) check(outbound string, inbound *Expression) error {
+	switch inbound.Operator {
+	case OperatorAnd:
+		for _, op := range inbound.Operands {
+			if err := c.check(outbound, op); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OperatorOr:
+		reasons := make([]string, 0, len(inbound.Operands))
+		for _, op := range inbound.Operands {
+			err := c.check(outbound, op)
+			if err == nil {
+				return nil
+			}
+			reasons = append(reasons, err.Error())
+		}
+		return fmt.Errorf("none of the alternatives in %s is compatible: %s", inbound, strings.Join(reasons, "; "))
+	}
+
+	// the exception may make an otherwise incompatible licence compatible
+	if inbound.Exception != "" {
+		withException := Canonical(inbound.String())
+		if ok, err := c.checkID(outbound, withException); ok {
+			return err
+		}
+	}
+
+	id := canonicalOrLater(inbound.Licence)
+	if ok, err := c.checkID(outbound, id); ok {
+		return err
+	}
+
+	category := CategoryOf(id)
+	if _, ok := c.categories[category]; ok {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %s", id, categoryReasons[category])
+}
+
+// checkID checks the licence ID against the explicit entries of the matrix. It returns false if there is no entry.
+func (c *compatibility) checkID(outbound, id string) (bool, error) {
+	if id == outbound {
+		return true, nil
+	}
+
+	if reason, ok := c.incompatible[id]; ok {
+		return true, fmt.Errorf("%s: %s", id, reason)
+	}
+
+	if _, ok := c.compatible[id]; ok {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// canonicalOrLater returns the canonical ID of the licence, converting the "+" suffix of licence IDs (e.g. GPL-2.0+) to
+// "-or-later".
+func canonicalOrLater(id string) string {
+	if base, ok := strings.CutSuffix(id, "+"); ok {
+		id = base + "-or-later"
+	}
+
+	return Canonical(id)
+}
@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	testCases := []struct {
+		outbound string
+		inbound  string
+		wantErr  string
+	}{
+		{outbound: "Apache-2.0", inbound: "MIT"},
+		{outbound: "Apache-2.0", inbound: "MPL-2.0"},
+		{outbound: "Apache-2.0", inbound: "GPL-2.0-only", wantErr: "strong copyleft"},
+		{outbound: "Apache-2.0", inbound: "AGPL-3.0", wantErr: "network copyleft"},
+		{outbound: "Apache-2.0", inbound: "SSPL-1.0", wantErr: "source-available"},
+		{outbound: "Apache-2.0", inbound: "Made-Up-1.0", wantErr: "unknown"},
+		{outbound: "Apache-2.0", inbound: "GPL-2.0 WITH Classpath-exception-2.0"},
+		{outbound: "Apache-2.0", inbound: "MIT OR GPL-3.0"},
+		{outbound: "Apache-2.0", inbound: "MIT AND GPL-3.0", wantErr: "GPL-3.0"},
+		{outbound: "Apache-2.0", inbound: "GPL-2.0 OR GPL-3.0", wantErr: "none of the alternatives"},
+		{outbound: "GPL-3.0", inbound: "GPL-2.0-only", wantErr: "version 2 of the GPL only"},
+		{outbound: "GPL-3.0", inbound: "GPL-2.0+"},
+		{outbound: "GPL-3.0", inbound: "Apache-2.0"},
+		{outbound: "GPL-3.0", inbound: "AGPL-3.0-only"},
+		{outbound: "GPL-3.0-only", inbound: "EPL-2.0", wantErr: "secondary licence"},
+		{outbound: "GPL-2.0", inbound: "Apache-2.0", wantErr: "patent termination"},
+		{outbound: "GPL-2.0+", inbound: "LGPL-2.1"},
+		{outbound: "Elastic-2.0", inbound: "BSD-3-Clause"},
+		{outbound: "Elastic-2.0", inbound: "MPL-2.0"},
+		{outbound: "Elastic-2.0", inbound: "Elastic-2.0"},
+		{outbound: "Elastic-2.0", inbound: "GPL-3.0", wantErr: "strong copyleft"},
+		{outbound: "Elastic-2.0", inbound: "SSPL-1.0", wantErr: "source-available"},
+		{outbound: "GPL-2.0-or-later", inbound: "GPL-3.0"},
+		{outbound: "GPL-2.0-or-later", inbound: "LGPL-3.0-only"},
+		{outbound: "GPL-2.0-or-later", inbound: "Apache-2.0"},
+		{outbound: "GPL-2.0-or-later", inbound: "AGPL-3.0", wantErr: "network copyleft"},
+		{outbound: "GPL-2.0-only", inbound: "GPL-3.0", wantErr: "version 3 of the GPL"},
+		{outbound: "LGPL-2.1-or-later", inbound: "Apache-2.0"},
+		{outbound: "LGPL-2.1-only", inbound: "Apache-2.0", wantErr: "patent termination"},
+		{outbound: "LGPL-2.1-or-later", inbound: "EPL-2.0", wantErr: "the EPL and the LGPL"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.outbound+" "+tc.inbound, func(t *testing.T) {
+			expr, err := ParseExpression(tc.inbound)
+			require.NoError(t, err)
+
+			err = CheckCompatibility(tc.outbound, expr)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestHasCompatibility(t *testing.T) {
+	require.True(t, HasCompatibility("Apache-2.0"))
+	require.True(t, HasCompatibility("GPL-3.0+"))
+	require.False(t, HasCompatibility("WTFPL"))
+
+	expr, err := ParseExpression("MIT")
+	require.NoError(t, err)
+	require.Error(t, CheckCompatibility("WTFPL", expr))
+}
@@ -0,0 +1,216 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licence
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operators of compound licence expressions.
+const (
+	OperatorAnd = "AND"
+	OperatorOr  = "OR"
+
+	operatorWith = "WITH"
+)
+
+// Expression is a parsed SPDX licence expression such as "MIT OR Apache-2.0" or "GPL-2.0-or-later WITH Classpath-exception-2.0".
+// A simple expression has a licence ID and an optional exception. A compound expression has an operator and operands.
+type Expression struct {
+	Licence   string        // licence ID of a simple expression
+	Exception string        // exception of a simple expression (e.g. Classpath-exception-2.0)
+	Operator  string        // OperatorAnd or OperatorOr for compound expressions
+	Operands  []*Expression // operands of a compound expression
+}
+
+// ParseExpression parses an SPDX licence expression. Operators are case-insensitive and WITH binds tighter than AND,
+// which binds tighter than OR. Consecutive words that are not operators form a single licence ID so that licence
+// names containing spaces, such as "Public Domain", can be used as well.
+func ParseExpression(s string) (*Expression, error) {
+	p := &expressionParser{tokens: tokenizeExpression(s)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty licence expression")
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid licence expression %q: %w", s, err)
+	}
+
+	if tok, ok := p.peek(); ok {
+		return nil, fmt.Errorf("invalid licence expression %q: unexpected %q", s, tok)
+	}
+
+	return expr, nil
+}
+
+// IsSimple returns true if the expression consists of a single licence ID and an optional exception.
+func (e *Expression) IsSimple() bool {
+	return e.Operator == ""
+}
+
+// String returns the expression in SPDX syntax.
+func (e *Expression) String() string {
+	if e.IsSimple() {
+		if e.Exception != "" {
+			return e.Licence + " " + operatorWith + " " + e.Exception
+		}
+		return e.Licence
+	}
+
+	parts := make([]string, len(e.Operands))
+	for i, op := range e.Operands {
+		parts[i] = op.String()
+		// AND binds tighter than OR so only nested OR expressions need parentheses
+		if !op.IsSimple() && op.Operator == OperatorOr && e.Operator == OperatorAnd {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+
+	return strings.Join(parts, " "+e.Operator+" ")
+}
+
+// Licences returns the IDs of all licences in the expression.
+func (e *Expression) Licences() []string {
+	if e.IsSimple() {
+		return []string{e.Licence}
+	}
+
+	var ids []string
+	for _, op := range e.Operands {
+		ids = append(ids, op.Licences()...)
+	}
+
+	return ids
+}
+
+func tokenizeExpression(s string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s)) {
+		tokens = append(tokens, field)
+	}
+
+	return tokens
+}
+
+type expressionParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *expressionParser) peek() (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+
+	return p.tokens[p.pos], true
+}
+
+func (p *expressionParser) accept(keyword string) bool {
+	if tok, ok := p.peek(); ok && strings.EqualFold(tok, keyword) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *expressionParser) parseOr() (*Expression, error) {
+	return p.parseCompound(OperatorOr, p.parseAnd)
+}
+
+func (p *expressionParser) parseAnd() (*Expression, error) {
+	return p.parseCompound(OperatorAnd, p.parseWith)
+}
+
+func (p *expressionParser) parseCompound(operator string, parseOperand func() (*Expression, error)) (*Expression, error) {
+	first, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []*Expression{first}
+	for p.accept(operator) {
+		next, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, next)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+
+	return &Expression{Operator: operator, Operands: operands}, nil
+}
+
+func (p *expressionParser) parseWith() (*Expression, error) {
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+
+		return expr, nil
+	}
+
+	id, err := p.parseID()
+	if err != nil {
+		return nil, err
+	}
+
+	expr := &Expression{Licence: id}
+	if p.accept(operatorWith) {
+		if expr.Exception, err = p.parseID(); err != nil {
+			return nil, err
+		}
+	}
+
+	return expr, nil
+}
+
+func (p *expressionParser) parseID() (string, error) {
+	var words []string
+	for {
+		tok, ok := p.peek()
+		if !ok || tok == "(" || tok == ")" || isOperator(tok) {
+			break
+		}
+		words = append(words, tok)
+		p.pos++
+	}
+
+	if len(words) == 0 {
+		if tok, ok := p.peek(); ok {
+			return "", fmt.Errorf("expected licence ID but found %q", tok)
+		}
+		return "", fmt.Errorf("expected licence ID but found end of expression")
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+func isOperator(tok string) bool {
+	return strings.EqualFold(tok, OperatorAnd) || strings.EqualFold(tok, OperatorOr) || strings.EqualFold(tok, operatorWith)
+}
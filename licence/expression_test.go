@@ -0,0 +1,57 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpression(t *testing.T) {
+	testCases := []struct {
+		expr         string
+		wantString   string
+		wantLicences []string
+	}{
+		{expr: "MIT", wantString: "MIT", wantLicences: []string{"MIT"}},
+		{expr: "Public Domain", wantString: "Public Domain", wantLicences: []string{"Public Domain"}},
+		{expr: "MIT OR Apache-2.0", wantString: "MIT OR Apache-2.0", wantLicences: []string{"MIT", "Apache-2.0"}},
+		{expr: "MIT and BSD-3-Clause or ISC", wantString: "MIT AND BSD-3-Clause OR ISC", wantLicences: []string{"MIT", "BSD-3-Clause", "ISC"}},
+		{expr: "MIT AND (BSD-3-Clause OR ISC)", wantString: "MIT AND (BSD-3-Clause OR ISC)", wantLicences: []string{"MIT", "BSD-3-Clause", "ISC"}},
+		{expr: "(GPL-2.0-or-later WITH Classpath-exception-2.0)", wantString: "GPL-2.0-or-later WITH Classpath-exception-2.0", wantLicences: []string{"GPL-2.0-or-later"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := ParseExpression(tc.expr)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantString, expr.String())
+			require.Equal(t, tc.wantLicences, expr.Licences())
+		})
+	}
+}
+
+func TestParseExpressionInvalid(t *testing.T) {
+	for _, s := range []string{"", "MIT OR", "AND MIT", "(MIT OR ISC", "MIT) OR ISC", "GPL-2.0 WITH", "MIT ()"} {
+		t.Run(s, func(t *testing.T) {
+			_, err := ParseExpression(s)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licence
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.elastic.co/go-licence-detector/assets"
+)
+
+// LicenceRefPrefix is the prefix of identifiers of licences that are not on the SPDX licence list.
+const LicenceRefPrefix = "LicenseRef-"
+
+// spdxFile represents the structure of the embedded SPDX licence list.
+type spdxFile struct {
+	Licences   []string          `json:"licences"`
+	Exceptions []string          `json:"exceptions"`
+	Deprecated map[string]string `json:"deprecated"` // replacements of deprecated licence IDs
+	Aliases    map[string]string `json:"aliases"`    // canonical IDs of licence names used by the classifier
+}
+
+// spdxList resolves licence IDs to their canonical SPDX identifiers.
+type spdxList struct {
+	canonical  map[string]string // canonical licence IDs keyed by the lower case current, deprecated or alias ID
+	exceptions map[string]string // canonical exception IDs keyed by the lower case exception ID
+	ids        []string          // current, deprecated and alias IDs, used for suggestions
+}
+
+var spdx = mustLoadSPDX(assets.SPDX)
+
+func mustLoadSPDX(data []byte) *spdxList {
+	var sf spdxFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		panic(fmt.Errorf("failed to unmarshal SPDX licence list: %w", err))
+	}
+
+	l := &spdxList{
+		canonical:  make(map[string]string, len(sf.Licences)+len(sf.Deprecated)+len(sf.Aliases)),
+		exceptions: make(map[string]string, len(sf.Exceptions)),
+	}
+
+	for _, id := range sf.Licences {
+		l.canonical[strings.ToLower(id)] = id
+		l.ids = append(l.ids, id)
+	}
+
+	for _, id := range sf.Exceptions {
+		l.exceptions[strings.ToLower(id)] = id
+	}
+
+	for id, replacement := range sf.Deprecated {
+		l.canonical[strings.ToLower(id)] = replacement
+		l.ids = append(l.ids, id)
+	}
+
+	for id, canonical := range sf.Aliases {
+		l.canonical[strings.ToLower(id)] = canonical
+		l.canonical[strings.ToLower(canonical)] = canonical
+		l.ids = append(l.ids, id)
+	}
+
+	// sort the IDs so that suggestions do not depend on the iteration order of the maps
+	slices.Sort(l.ids)
+
+	return l
+}
+
+// Canonical returns the canonical SPDX identifier of the given licence ID. IDs are matched case-insensitively,
+// deprecated IDs are replaced by their successors (e.g. GPL-2.0 by GPL-2.0-only) and names of licences that are not on
+// the SPDX list, such as "Public Domain", are replaced by LicenseRef- identifiers. A licence ID with an exception
+// (e.g. "GPL-2.0 WITH Classpath-exception-2.0") is normalised as well. Unknown IDs are returned unchanged.
+func Canonical(id string) string {
+	canonical, _ := spdx.resolve(id)
+	return canonical
+}
+
+// IsKnownID returns true if the given licence ID is on the SPDX licence list, a deprecated SPDX ID, a licence name used
+// by the classifier or a LicenseRef- identifier.
+func IsKnownID(id string) bool {
+	_, ok := spdx.resolve(id)
+	return ok
+}
+
+// NormaliseExpression validates the licence IDs and exceptions of the SPDX licence expression and returns the
+// expression with canonical identifiers. The error suggests the nearest valid ID for unknown IDs.
+func NormaliseExpression(s string) (string, error) {
+	expr, err := ParseExpression(s)
+	if err != nil {
+		return "", err
+	}
+
+	normalised, err := spdx.normalise(expr)
+	if err != nil {
+		return "", err
+	}
+
+	return normalised.String(), nil
+}
+
+// Suggest returns the valid licence ID nearest to the given unknown ID, or an empty string if none is close enough.
+func Suggest(id string) string {
+	return spdx.suggest(id)
+}
+
+func (l *spdxList) resolve(id string) (string, bool) {
+	if canonical, ok := l.canonical[strings.ToLower(id)]; ok {
+		return canonical, true
+	}
+
+	if strings.HasPrefix(id, LicenceRefPrefix) {
+		return id, true
+	}
+
+	// the deprecated "+" suffix is the "or later" operator of licences without an -or-later ID
+	if base, ok := strings.CutSuffix(id, "+"); ok {
+		if canonical, ok := l.canonical[strings.ToLower(base)]; ok {
+			return canonical + "+", true
+		}
+	}
+
+	if expr, err := ParseExpression(id); err == nil && expr.IsSimple() && expr.Exception != "" {
+		if normalised, err := l.normalise(expr); err == nil {
+			return normalised.String(), true
+		}
+	}
+
+	return id, false
+}
+
+func (l *spdxList) normalise(expr *Expression) (*Expression, error) {
+	if !expr.IsSimple() {
+		operands := make([]*Expression, len(expr.Operands))
+		for i, op := range expr.Operands {
+			normalised, err := l.normalise(op)
+			if err != nil {
+				return nil, err
+			}
+			operands[i] = normalised
+		}
+
+		return &Expression{Operator: expr.Operator, Operands: operands}, nil
+	}
+
+	canonical, ok := l.resolve(expr.Licence)
+	if !ok {
+		if suggestion := l.suggest(expr.Licence); suggestion != "" {
+			return nil, fmt.Errorf("unknown licence ID %q. Did you mean %q?", expr.Licence, suggestion)
+		}
+		return nil, fmt.Errorf("unknown licence ID %q. Use the %s prefix for licences that are not on the SPDX licence list", expr.Licence, LicenceRefPrefix)
+	}
+
+	if expr.Exception == "" {
+		// replacements of deprecated IDs may include an exception
+		return ParseExpression(canonical)
+	}
+
+	exception, ok := l.exceptions[strings.ToLower(expr.Exception)]
+	if !ok {
+		return nil, fmt.Errorf("unknown licence exception %q", expr.Exception)
+	}
+
+	// the licence ID may already have been replaced by an ID with an exception
+	normalised, err := ParseExpression(canonical)
+	if err != nil || normalised.Exception != "" {
+		return nil, fmt.Errorf("licence %s cannot have the exception %s", expr.Licence, exception)
+	}
+	normalised.Exception = exception
+
+	return normalised, nil
+}
+
+// suggest returns the canonical ID of the licence ID nearest to the given ID. IDs starting with the given ID are
+// preferred, otherwise the edit distance between the IDs must not exceed a third of the length of the given ID.
+func (l *spdxList) suggest(id string) string {
+	normalised := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(id), " ", "-"))
+	if normalised == "" {
+		return ""
+	}
+
+	if canonical, ok := l.canonical[normalised]; ok {
+		return canonical
+	}
+
+	var best string
+	bestDistance := max(2, len(normalised)/3) + 1
+	for _, candidate := range l.ids {
+		lower := strings.ToLower(candidate)
+		if strings.HasPrefix(lower, normalised) {
+			if best == "" || !strings.HasPrefix(strings.ToLower(best), normalised) || len(candidate) < len(best) {
+				best = candidate
+				bestDistance = 0
+			}
+			continue
+		}
+
+		if bestDistance == 0 {
+			continue
+		}
+
+		if d := editDistance(normalised, lower); d < bestDistance {
+			best = candidate
+			bestDistance = d
+		}
+	}
+
+	if best == "" {
+		return ""
+	}
+
+	return l.canonical[strings.ToLower(best)]
+}
+
+// editDistance returns the optimal string alignment distance between the given strings, which is the Levenshtein
+// distance counting the transposition of adjacent characters as a single edit.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
@@ -0,0 +1,100 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonical(t *testing.T) {
+	testCases := []struct {
+		id      string
+		want    string
+		unknown bool
+	}{
+		{id: "MIT", want: "MIT"},
+		{id: "apache-2.0", want: "Apache-2.0"},
+		{id: "GPL-2.0", want: "GPL-2.0-only"},
+		{id: "GPL-2.0+", want: "GPL-2.0-or-later"},
+		{id: "LGPL-2.1", want: "LGPL-2.1-only"},
+		{id: "AGPL-3.0", want: "AGPL-3.0-only"},
+		{id: "GPL-2.0-with-classpath-exception", want: "GPL-2.0-only WITH Classpath-exception-2.0"},
+		{id: "GPL-2.0 WITH classpath-exception-2.0", want: "GPL-2.0-only WITH Classpath-exception-2.0"},
+		{id: "MPL-1.1+", want: "MPL-1.1+"},
+		{id: "Public Domain", want: "LicenseRef-Public-Domain"},
+		{id: "Facebook-2-Clause", want: "LicenseRef-Facebook-2-Clause"},
+		{id: "LicenseRef-Internal", want: "LicenseRef-Internal"},
+		{id: "Apache 2.0", want: "Apache 2.0", unknown: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			require.Equal(t, tc.want, Canonical(tc.id))
+			require.Equal(t, !tc.unknown, IsKnownID(tc.id))
+		})
+	}
+}
+
+func TestNormaliseExpression(t *testing.T) {
+	testCases := []struct {
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{expr: "MIT OR GPL-2.0", want: "MIT OR GPL-2.0-only"},
+		{expr: "(mit AND bsd-3-clause) OR Public Domain", want: "MIT AND BSD-3-Clause OR LicenseRef-Public-Domain"},
+		{expr: "GPL-2.0-with-GCC-exception", want: "GPL-2.0-or-later WITH GCC-exception-2.0"},
+		{expr: "Apache 2.0", wantErr: `unknown licence ID "Apache 2.0". Did you mean "Apache-2.0"?`},
+		{expr: "BSD-3", wantErr: `unknown licence ID "BSD-3". Did you mean "BSD-3-Clause"?`},
+		{expr: "MTI", wantErr: `unknown licence ID "MTI". Did you mean "MIT"?`},
+		{expr: "Internal Licence", wantErr: `unknown licence ID "Internal Licence". Use the LicenseRef- prefix`},
+		{expr: "GPL-2.0 WITH No-exception", wantErr: `unknown licence exception "No-exception"`},
+		{expr: "GPL-2.0-with-classpath-exception WITH GCC-exception-2.0", wantErr: "cannot have the exception"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			have, err := NormaliseExpression(tc.expr)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, have)
+		})
+	}
+}
+
+func TestEmbeddedLicenceIDsAreKnown(t *testing.T) {
+	for id := range categories {
+		require.True(t, IsKnownID(id), "unknown licence ID %s in categories", id)
+	}
+
+	for id, c := range compatibilityMatrix {
+		require.True(t, IsKnownID(id), "unknown outbound licence ID %s", id)
+		for compatible := range c.compatible {
+			require.True(t, IsKnownID(compatible), "unknown compatible licence ID %s", compatible)
+		}
+		for incompatible := range c.incompatible {
+			require.True(t, IsKnownID(incompatible), "unknown incompatible licence ID %s", incompatible)
+		}
+	}
+}
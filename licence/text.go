@@ -0,0 +1,50 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licence
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"go.elastic.co/go-licence-detector/assets"
+)
+
+// textDirs lists the embedded directories holding licence texts named <licence ID>.txt.
+var textDirs = []struct {
+	fsys fs.FS
+	dir  string
+}{
+	{fsys: assets.Texts, dir: "texts"},
+	{fsys: assets.Licences, dir: "licences"},
+}
+
+// Text returns the canonical text of the licence with the given ID. The -or-later variants of a licence share the text
+// of the licence. It returns false for licence expressions and licences without a known text.
+func Text(id string) (string, bool) {
+	canonical := canonicalOrLater(id)
+	for _, name := range []string{canonical, strings.Replace(canonical, "-or-later", "-only", 1)} {
+		for _, td := range textDirs {
+			if text, err := fs.ReadFile(td.fsys, path.Join(td.dir, name+".txt")); err == nil {
+				return string(text), true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licence
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/go-licence-detector/assets"
+)
+
+func TestText(t *testing.T) {
+	testCases := []struct {
+		id       string
+		wantText string
+	}{
+		{id: "MIT", wantText: "Permission is hereby granted, free of charge"},
+		{id: "mit", wantText: "Permission is hereby granted, free of charge"},
+		{id: "GPL-3.0", wantText: "GNU GENERAL PUBLIC LICENSE"},
+		{id: "GPL-3.0-or-later", wantText: "GNU GENERAL PUBLIC LICENSE"},
+		{id: "LGPL-2.1+", wantText: "GNU LESSER GENERAL PUBLIC LICENSE"},
+		{id: "Elastic-2.0", wantText: "Elastic License 2.0"},
+		{id: "MIT OR Apache-2.0"},
+		{id: "GPL-2.0-only WITH Classpath-exception-2.0"},
+		{id: "LicenseRef-Public-Domain"},
+		{id: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			text, ok := Text(tc.id)
+			if tc.wantText == "" {
+				require.False(t, ok)
+				return
+			}
+
+			require.True(t, ok)
+			require.Contains(t, text, tc.wantText)
+		})
+	}
+}
+
+func TestEmbeddedTextsAreNamedByCanonicalID(t *testing.T) {
+	entries, err := fs.ReadDir(assets.Texts, "texts")
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), ".txt")
+		require.Equal(t, id, Canonical(id))
+	}
+}
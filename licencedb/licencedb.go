@@ -0,0 +1,335 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package licencedb builds and inspects licence databases in the archive format used by the licence classifier.
+package licencedb // import "go.elastic.co/go-licence-detector/licencedb"
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/licenseclassifier"
+	"github.com/google/licenseclassifier/stringclassifier/searchset"
+)
+
+const (
+	// textExt is the extension of licence text files. It is also used to name the text entries in the archive.
+	textExt = ".txt"
+	// hashExt is the extension used to name the entries holding the precomputed hashes of a licence text.
+	hashExt = ".hash"
+	// headerSuffix marks licence header variants. The classifier reports them under the name of the main licence.
+	headerSuffix = ".header"
+	// deprecatedPrefix is the prefix used by spdx/license-list-data for the texts of deprecated licence IDs.
+	deprecatedPrefix = "deprecated_"
+)
+
+var errNoLicences = errors.New("no licence texts found")
+
+// BuildOptions control which licence texts are included in the database.
+type BuildOptions struct {
+	// IncludeDeprecated includes the texts of deprecated SPDX licence IDs (files prefixed with deprecated_).
+	IncludeDeprecated bool
+}
+
+// BuildFromDir builds a licence database from the licence texts in the given directory and writes it to w.
+// Each text must be in a file named <licence ID>.txt. If the directory does not contain any texts but has
+// a text subdirectory (as in a checkout of https://github.com/spdx/license-list-data), that is used instead.
+func BuildFromDir(dir string, w io.Writer, opts BuildOptions) error {
+	files, err := findLicenceTexts(dir, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		files, err = findLicenceTexts(filepath.Join(dir, "text"), opts)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("%w in %s", errNoLicences, dir)
+	}
+
+	return Build(w, files...)
+}
+
+func findLicenceTexts(dir string, opts BuildOptions) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read licence directory: %w", err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != textExt {
+			continue
+		}
+
+		if !opts.IncludeDeprecated && strings.HasPrefix(e.Name(), deprecatedPrefix) {
+			continue
+		}
+
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+
+	return files, nil
+}
+
+// Build builds a licence database from the given licence text files and writes it to w.
+// The licence ID is derived from the file name by removing the .txt extension.
+func Build(w io.Writer, files ...string) error {
+	texts := make(map[string][]byte, len(files))
+	for _, f := range files {
+		contents, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("failed to read licence text: %w", err)
+		}
+
+		texts[licenceID(filepath.Base(f))] = contents
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	if err := writeTexts(tw, texts); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write licence database: %w", err)
+	}
+
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("failed to write licence database: %w", err)
+	}
+
+	return nil
+}
+
+// Append returns a copy of the given licence database with the licence texts found at the root of fsys added to it.
+// Texts must be in files named <licence ID>.txt and replace any licence with the same ID in the database.
+func Append(archive []byte, fsys fs.FS) ([]byte, error) {
+	files, err := fs.Glob(fsys, "*"+textExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find licence texts: %w", err)
+	}
+
+	texts := make(map[string][]byte, len(files))
+	for _, f := range files {
+		contents, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read licence text: %w", err)
+		}
+
+		texts[licenceID(f)] = contents
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read licence database: %w", err)
+	}
+	defer gr.Close()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read licence database: %w", err)
+		}
+
+		if _, ok := texts[strings.TrimSuffix(hdr.Name, filepath.Ext(hdr.Name))]; ok {
+			continue
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("failed to write header of %s: %w", hdr.Name, err)
+		}
+
+		if _, err := io.Copy(tw, tr); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", hdr.Name, err)
+		}
+	}
+
+	if err := writeTexts(tw, texts); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write licence database: %w", err)
+	}
+
+	if err := gw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write licence database: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeTexts normalizes each licence text the same way as the classifier does and writes it to the archive
+// along with the hashes of its substrings. Entries are written in the order of their IDs to keep the output stable.
+func writeTexts(tw *tar.Writer, texts map[string][]byte) error {
+	ids := make([]string, 0, len(texts))
+	for id := range texts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		str := licenseclassifier.TrimExtraneousTrailingText(string(texts[id]))
+		for _, n := range licenseclassifier.Normalizers {
+			str = n(str)
+		}
+
+		if err := writeEntry(tw, id+textExt, []byte(str)); err != nil {
+			return err
+		}
+
+		var hashes bytes.Buffer
+		if err := searchset.New(str, searchset.DefaultGranularity).Serialize(&hashes); err != nil {
+			return fmt.Errorf("failed to compute hashes of %s: %w", id, err)
+		}
+
+		if err := writeEntry(tw, id+hashExt, hashes.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, name string, contents []byte) error {
+	hdr := &tar.Header{
+		Name: name,
+		Mode: 0o644,
+		Size: int64(len(contents)),
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write header of %s: %w", name, err)
+	}
+
+	if _, err := tw.Write(contents); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// List returns the sorted IDs of all licences known to the given licence database.
+// Header variants are reported under the ID of the main licence, as the classifier does.
+func List(archive io.Reader) ([]string, error) {
+	gr, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read licence database: %w", err)
+	}
+	defer gr.Close()
+
+	seen := make(map[string]struct{})
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read licence database: %w", err)
+		}
+
+		if filepath.Ext(hdr.Name) != textExt {
+			continue
+		}
+
+		seen[strings.TrimSuffix(licenceID(hdr.Name), headerSuffix)] = struct{}{}
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// Hash returns the SHA-256 hash of the licence texts of the given licence database. The hashes precomputed for each
+// text are left out as their encoding is not stable, so databases holding the same texts have the same hash.
+func Hash(archive io.Reader) (string, error) {
+	gr, err := gzip.NewReader(archive)
+	if err != nil {
+		return "", fmt.Errorf("failed to read licence database: %w", err)
+	}
+	defer gr.Close()
+
+	texts := make(map[string][sha256.Size]byte)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return "", fmt.Errorf("failed to read licence database: %w", err)
+		}
+
+		if filepath.Ext(hdr.Name) != textExt {
+			continue
+		}
+
+		contents, err := io.ReadAll(tr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+		texts[hdr.Name] = sha256.Sum256(contents)
+	}
+
+	names := make([]string, 0, len(texts))
+	for name := range texts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		sum := texts[name]
+		fmt.Fprintf(h, "%s\x00%x\n", name, sum)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func licenceID(fileName string) string {
+	return strings.TrimPrefix(strings.TrimSuffix(fileName, textExt), deprecatedPrefix)
+}
@@ -0,0 +1,128 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package licencedb
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/licenseclassifier"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildFromDir(t *testing.T) {
+	testCases := []struct {
+		name    string
+		opts    BuildOptions
+		wantIDs []string
+	}{
+		{
+			name:    "WithoutDeprecated",
+			wantIDs: []string{"ISC", "MIT"},
+		},
+		{
+			name:    "WithDeprecated",
+			opts:    BuildOptions{IncludeDeprecated: true},
+			wantIDs: []string{"ISC", "MIT", "MIT-Old"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, BuildFromDir("testdata/licences", &buf, tc.opts))
+
+			haveIDs, err := List(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, tc.wantIDs, haveIDs)
+		})
+	}
+}
+
+func TestBuildFromDirWithoutTexts(t *testing.T) {
+	var buf bytes.Buffer
+	require.ErrorIs(t, BuildFromDir(t.TempDir(), &buf, BuildOptions{}), errNoLicences)
+}
+
+func TestBuiltDatabaseClassifies(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, BuildFromDir("testdata/licences/text", &buf, BuildOptions{}))
+
+	classifier, err := licenseclassifier.New(0.85, licenseclassifier.ArchiveBytes(buf.Bytes()))
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile("testdata/licences/text/ISC.txt")
+	require.NoError(t, err)
+
+	matches := classifier.MultipleMatch(string(contents), true)
+	require.NotEmpty(t, matches)
+	require.Equal(t, "ISC", matches[0].Name)
+}
+
+func TestAppend(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, BuildFromDir("testdata/licences", &buf, BuildOptions{}))
+
+	mit, err := os.ReadFile("testdata/licences/text/MIT.txt")
+	require.NoError(t, err)
+
+	extra := fstest.MapFS{
+		"MIT.txt":     {Data: mit},
+		"MIT-Alt.txt": {Data: mit},
+		"README.md":   {Data: []byte("not a licence")},
+	}
+
+	archive, err := Append(buf.Bytes(), extra)
+	require.NoError(t, err)
+
+	haveIDs, err := List(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.Equal(t, []string{"ISC", "MIT", "MIT-Alt"}, haveIDs)
+}
+
+func TestHash(t *testing.T) {
+	build := func() []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		require.NoError(t, BuildFromDir("testdata/licences", &buf, BuildOptions{}))
+		return buf.Bytes()
+	}
+
+	first, second := build(), build()
+
+	firstHash, err := Hash(bytes.NewReader(first))
+	require.NoError(t, err)
+
+	// databases with the same texts have the same hash even if the encoding of their precomputed hashes differs
+	secondHash, err := Hash(bytes.NewReader(second))
+	require.NoError(t, err)
+	require.Equal(t, firstHash, secondHash)
+
+	mit, err := os.ReadFile("testdata/licences/text/MIT.txt")
+	require.NoError(t, err)
+
+	archive, err := Append(first, fstest.MapFS{"MIT-Alt.txt": {Data: mit}})
+	require.NoError(t, err)
+
+	appendedHash, err := Hash(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.NotEqual(t, firstHash, appendedHash)
+}
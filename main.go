@@ -18,86 +18,334 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"go.elastic.co/go-licence-detector/cache"
 	"go.elastic.co/go-licence-detector/dependency"
 	"go.elastic.co/go-licence-detector/detector"
+	"go.elastic.co/go-licence-detector/licence"
+	"go.elastic.co/go-licence-detector/policy"
 	"go.elastic.co/go-licence-detector/render"
 	"go.elastic.co/go-licence-detector/validate"
 )
 
+// exitCodeReviewRequired is the exit code used when dependencies with maybelisted licences need to be reviewed.
+const exitCodeReviewRequired = 3
+
 var (
+	approvalsFlag       = flag.String("approvals", "", "Path to the file containing approved reviews of maybelisted licences.")
+	cacheDirFlag        = flag.String("cacheDir", "", "Path to the directory caching the detected licences of module versions. Uses go-licence-detector in the user cache directory if empty.")
 	depsTemplateFlag    = flag.String("depsTemplate", "example/templates/dependencies.asciidoc.tmpl", "Path to the dependency list template file.")
-	depsOutFlag         = flag.String("depsOut", "", "Path to output the dependency list.")
+	depsOutFlag         = flag.String("depsOut", "", "Path to output the dependency list. With several profiles, the name of each profile is inserted before the extension.")
 	inFlag              = flag.String("in", "-", "Dependency list (output from go list -m -json all).")
 	includeIndirectFlag = flag.Bool("includeIndirect", false, "Include indirect dependencies.")
 	licenceDataFlag     = flag.String("licenceData", "", "Path to the licence database. Uses embedded database if empty.")
+	searchDepthFlag     = flag.Int("licenceSearchDepth", detector.DefaultSearchDepth, "Maximum depth of the directories searched for the licence file of a dependency, the module root being at depth 1.")
+	noCacheFlag         = flag.Bool("noCache", false, "Detect the licences of all modules without reading or writing the cache.")
 	noticeTemplateFlag  = flag.String("noticeTemplate", "example/templates/NOTICE.txt.tmpl", "Path to the NOTICE template file.")
-	noticeOutFlag       = flag.String("noticeOut", "", "Path to output the notice.")
-	overridesFlag       = flag.String("overrides", "", "Path to the file containing override directives.")
+	noticeOutFlag       = flag.String("noticeOut", "", "Path to output the notice. With several profiles, the name of each profile is inserted before the extension.")
+	outboundLicenceFlag = flag.String("outboundLicence", "", "Licence of the project used to check the compatibility of dependencies. Detected from the licence file of the main module if empty.")
+	packagesFlag        = flag.String("packages", "", "Package list (output from go list -deps -test -json ./... tool) used to identify test-only and tool dependencies.")
+	reportOutFlag       = flag.String("reportOut", "", "Path to output a JSON report of the dependencies and the licence exceptions used.")
 	rulesFlag           = flag.String("rules", "", "Path to file containing rules regarding licence types. Uses embedded rules if empty.")
 	validateFlag        = flag.Bool("validate", false, "Validate results (slow).")
+	workersFlag         = flag.Int("workers", 0, "Number of dependencies whose licence is detected concurrently. Uses the number of CPUs if zero.")
 
+	overridesFlags    stringsFlag
+	profileFlags      stringsFlag
 	templateKeyValues render.KeyValueFlags
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "db":
+			runDB(os.Args[2:])
+			return
+		case "overrides":
+			runOverrides(os.Args[2:])
+			return
+		case "fix":
+			runFix(os.Args[2:])
+			return
+		case "cache":
+			runCache(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Var(&overridesFlags, "overrides", "Path to the file containing override directives. Can be repeated or given as a comma-separated list, with later files overriding the fields set by earlier files.")
+	flag.Var(&profileFlags, "profile", "Name of the rules profile to use. Can be repeated or given as a comma-separated list to report the results of several profiles. Uses the base rules if empty.")
 	flag.Var(&templateKeyValues, "template-value", "Can be used in template to pass in a version number or similar information. Example: --template-value=key1=value1 and {{TemplateValue \"key1\"}}.")
 	flag.Parse()
 
-	// create reader for dependency information
-	depInput, err := mkReader(*inFlag)
+	profiles := []string(profileFlags)
+	if len(profiles) == 0 {
+		profiles = []string{""}
+	}
+
+	// read dependency information once as it is used for each profile
+	depData, err := readInput(*inFlag)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *inFlag, err)
+	}
+
+	// read package information if provided
+	var pkgData []byte
+	if *packagesFlag != "" {
+		if pkgData, err = readInput(*packagesFlag); err != nil {
+			log.Fatalf("Failed to read %s: %v", *packagesFlag, err)
+		}
+	}
+
+	// load the licence database once so that the cache is keyed by the hash of the database used by the classifier
+	licenceDB, err := detector.LoadLicenceDB(*licenceDataFlag)
 	if err != nil {
-		log.Fatalf("Failed to create reader for %s: %v", *inFlag, err)
+		log.Fatalf("Failed to load licence database: %v", err)
 	}
-	defer depInput.Close()
 
 	// create licence classifier
-	classifier, err := detector.NewClassifier(*licenceDataFlag)
+	classifier, err := detector.NewClassifierFromDB(licenceDB)
 	if err != nil {
 		log.Fatalf("Failed to create licence classifier: %v", err)
 	}
 
+	// open the cache shared by all profiles
+	cacheOpt := openCache(licenceDB)
+
 	// load overrides
-	overrides, err := dependency.LoadOverrides(*overridesFlag)
+	overrides, err := dependency.LoadOverrides(overridesFlags...)
 	if err != nil {
 		log.Fatalf("Failed to load overrides: %v", err)
 	}
 
-	// load rules
-	rules, err := detector.LoadRules(*rulesFlag)
+	// load approvals
+	approvals, err := detector.LoadApprovals(*approvalsFlag)
 	if err != nil {
-		log.Fatalf("Failed to load rules: %v", err)
+		log.Fatalf("Failed to load approvals: %v", err)
 	}
 
-	// detect dependencies
-	dependencies, err := detector.Detect(depInput, classifier, rules, overrides, *includeIndirectFlag)
-	if err != nil {
-		log.Fatalf("Failed to detect licences: %v", err)
+	if *outboundLicenceFlag != "" && !licence.HasCompatibility(*outboundLicenceFlag) {
+		log.Fatalf("No compatibility information for outbound licence %s", *outboundLicenceFlag)
 	}
 
-	if *validateFlag {
-		if err := validate.Validate(dependencies); err != nil {
-			log.Fatalf("Validation failed: %v", err)
+	var (
+		results        = make([]render.ProfileResult, 0, len(profiles))
+		failedProfiles = 0
+		policyErrors   = 0
+		pendingReviews = 0
+	)
+
+	for _, profile := range profiles {
+		// prefix log messages with the profile name when detecting with several profiles
+		if len(profiles) > 1 {
+			log.SetFlags(log.Flags() | log.Lmsgprefix)
+			log.SetPrefix("[" + profile + "] ")
+		}
+
+		deps, err := detectProfile(profile, depData, pkgData, classifier, overrides, approvals, cacheOpt)
+		if err != nil {
+			if len(profiles) == 1 {
+				log.Fatalf("Detection failed: %v", err)
+			}
+
+			log.Printf("Detection failed: %v", err)
+			results = append(results, render.ProfileResult{Profile: profile, Error: err.Error()})
+			failedProfiles++
+			continue
+		}
+
+		for _, w := range deps.Warnings {
+			log.Printf("WARNING: %s", w)
+		}
+
+		for _, e := range deps.Exceptions {
+			log.Printf("Licence exception used: %s uses %s until %s (approved by %s: %s)", e.Module, e.Licence, e.Expires, e.Approver, e.Justification)
+		}
+
+		for _, e := range deps.Excluded {
+			log.Printf("Dependency excluded: %s@%s by override %s: %s", e.Module, e.Version, e.Override, e.Reason)
+		}
+
+		for _, r := range deps.PendingReviews {
+			log.Printf("WARNING: Review required: %s@%s uses maybelisted licence %s", r.Module, r.Version, r.Licence)
+		}
+
+		for _, depInfoList := range [][]dependency.Info{deps.Direct, deps.Indirect} {
+			for _, depInfo := range depInfoList {
+				for _, w := range depInfo.Warnings {
+					log.Printf("WARNING: %s@%s: %s", depInfo.Name, depInfo.Version, w)
+				}
+
+				for _, r := range depInfo.PolicyResults {
+					log.Printf("%s: Policy %s: %s@%s: %s", strings.ToUpper(r.Severity), r.Policy, depInfo.Name, depInfo.Version, r.Message)
+					if r.Severity == policy.SeverityError {
+						policyErrors++
+					}
+				}
+			}
 		}
+
+		pendingReviews += len(deps.PendingReviews)
+		results = append(results, render.ProfileResult{Profile: profile, Dependencies: deps})
 	}
 
-	// only generate notice file if the output path is provided
-	if *noticeOutFlag != "" {
-		if err := render.Template(dependencies, templateKeyValues, *noticeTemplateFlag, *noticeOutFlag); err != nil {
-			log.Fatalf("Failed to render notice: %v", err)
+	log.SetPrefix("")
+
+	// the report of several profiles includes the errors so it is generated even if all of them failed
+	if len(profiles) > 1 && *reportOutFlag != "" {
+		if err := render.JSONProfiles(results, *reportOutFlag); err != nil {
+			log.Fatalf("Failed to render report: %v", err)
 		}
 	}
 
-	// only generate dependency listing if the output path is provided
-	if *depsOutFlag != "" {
-		if err := render.Template(dependencies, templateKeyValues, *depsTemplateFlag, *depsOutFlag); err != nil {
-			log.Fatalf("Failed to render dependency list: %v", err)
+	if failedProfiles == len(profiles) {
+		log.Fatalf("Failed to detect licences with all %d profiles", len(profiles))
+	}
+
+	for _, result := range results {
+		dependencies := result.Dependencies
+		if dependencies == nil {
+			continue
+		}
+
+		// with several profiles, the outputs of each profile are written next to the given paths
+		outPath := func(path string) string { return path }
+		if len(profiles) > 1 {
+			log.SetPrefix("[" + result.Profile + "] ")
+			outPath = func(path string) string { return profileOutput(path, result.Profile) }
 		}
+
+		if *validateFlag {
+			if err := validate.Validate(dependencies); err != nil {
+				log.Fatalf("Validation failed: %v", err)
+			}
+		}
+
+		// only generate notice file if the output path is provided
+		if *noticeOutFlag != "" {
+			if err := render.Template(dependencies, templateKeyValues, *noticeTemplateFlag, outPath(*noticeOutFlag)); err != nil {
+				log.Fatalf("Failed to render notice: %v", err)
+			}
+		}
+
+		// only generate the report if the output path is provided
+		if len(profiles) == 1 && *reportOutFlag != "" {
+			if err := render.JSON(dependencies, *reportOutFlag); err != nil {
+				log.Fatalf("Failed to render report: %v", err)
+			}
+		}
+
+		// only generate dependency listing if the output path is provided
+		if *depsOutFlag != "" {
+			if err := render.Template(dependencies, templateKeyValues, *depsTemplateFlag, outPath(*depsOutFlag)); err != nil {
+				log.Fatalf("Failed to render dependency list: %v", err)
+			}
+		}
+	}
+
+	log.SetPrefix("")
+
+	if failedProfiles > 0 {
+		log.Fatalf("Failed to detect licences with %d of %d profiles", failedProfiles, len(profiles))
 	}
+
+	if policyErrors > 0 {
+		log.Fatalf("%d policy violations with severity %s", policyErrors, policy.SeverityError)
+	}
+
+	if pendingReviews > 0 {
+		log.Printf("%d dependencies require a licence review. Record the approvals in the approvals file.", pendingReviews)
+		os.Exit(exitCodeReviewRequired)
+	}
+}
+
+// profileOutput returns the path of an output of the given profile, which is the path with the profile name inserted
+// before its extension. The path is unchanged for the base rules.
+func profileOutput(path, profile string) string {
+	if profile == "" {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// detectProfile detects the dependencies using the rules of the given profile.
+func detectProfile(profile string, depData, pkgData []byte, classifier detector.Classifier, overrides dependency.Overrides, approvals *detector.Approvals, cacheOpt detector.Option) (*dependency.List, error) {
+	rules, err := detector.LoadProfile(*rulesFlag, profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	if *outboundLicenceFlag != "" {
+		rules.OutboundLicence = licence.Canonical(*outboundLicenceFlag)
+	}
+	rules.Approvals = approvals
+
+	var pkgInput io.Reader
+	if pkgData != nil {
+		pkgInput = bytes.NewReader(pkgData)
+	}
+
+	d, err := detector.New(
+		detector.WithClassifier(classifier),
+		detector.WithRules(rules),
+		detector.WithOverrides(overrides),
+		detector.WithIndirect(*includeIndirectFlag),
+		detector.WithWorkers(*workersFlag),
+		detector.WithSearchDepth(*searchDepthFlag),
+		cacheOpt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create detector: %w", err)
+	}
+
+	dependencies, err := d.Detect(context.Background(), detector.Source{Modules: bytes.NewReader(depData), Packages: pkgInput})
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect licences: %w", err)
+	}
+
+	return dependencies, nil
+}
+
+// openCache returns the option setting the cache of the detector for the given licence database. Detection continues
+// without the cache if it cannot be opened.
+func openCache(licenceDB []byte) detector.Option {
+	if *noCacheFlag {
+		return detector.WithCache(nil, "")
+	}
+
+	c, err := cache.Open(*cacheDirFlag)
+	if err != nil {
+		log.Printf("WARNING: Detecting licences without cache: %v", err)
+		return detector.WithCache(nil, "")
+	}
+
+	dbHash, err := detector.ClassifierHash(licenceDB)
+	if err != nil {
+		log.Fatalf("Failed to hash licence database: %v", err)
+	}
+
+	return detector.WithCache(c, dbHash)
+}
+
+func readInput(path string) ([]byte, error) {
+	r, err := mkReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
 }
 
 func mkReader(path string) (io.ReadCloser, error) {
@@ -107,3 +355,22 @@ func mkReader(path string) (io.ReadCloser, error) {
 
 	return os.Open(path)
 }
+
+// stringsFlag is a flag.Value that collects the values of a repeated flag. Comma-separated values are split.
+type stringsFlag []string
+
+func (sf *stringsFlag) String() string {
+	if sf == nil {
+		return ""
+	}
+	return strings.Join(*sf, ",")
+}
+
+func (sf *stringsFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*sf = append(*sf, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/detector"
+)
+
+const overridesUsage = `Usage: go-licence-detector overrides <command> [FLAGS]
+
+Commands:
+  check    Report unused and redundant overrides and missing licence files.
+`
+
+// runOverrides handles the overrides subcommand.
+func runOverrides(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, overridesUsage)
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "check":
+		runOverridesCheck(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown overrides command: %s\n\n%s", args[0], overridesUsage)
+		os.Exit(2)
+	}
+}
+
+func runOverridesCheck(args []string) {
+	fs := flag.NewFlagSet("overrides check", flag.ExitOnError)
+	inFlag := fs.String("in", "-", "Dependency list (output from go list -m -json all).")
+	licenceDataFlag := fs.String("licenceData", "", "Path to the licence database. Uses embedded database if empty.")
+	prunedOutFlag := fs.String("prunedOut", "", "Path to output the overrides without the unused overrides and redundant licence types.")
+	searchDepthFlag := fs.Int("licenceSearchDepth", detector.DefaultSearchDepth, "Maximum depth of the directories searched for the licence file of a dependency, the module root being at depth 1.")
+	var overridesFlags stringsFlag
+	fs.Var(&overridesFlags, "overrides", "Path to the file containing override directives. Can be repeated or given as a comma-separated list, with later files overriding the fields set by earlier files.")
+	_ = fs.Parse(args)
+
+	if len(overridesFlags) == 0 {
+		log.Fatal("Overrides file must be provided with -overrides")
+	}
+
+	depData, err := readInput(*inFlag)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *inFlag, err)
+	}
+
+	classifier, err := detector.NewClassifier(*licenceDataFlag)
+	if err != nil {
+		log.Fatalf("Failed to create licence classifier: %v", err)
+	}
+
+	overrides, err := dependency.LoadOverrides(overridesFlags...)
+	if err != nil {
+		log.Fatalf("Failed to load overrides: %v", err)
+	}
+
+	issues, err := detector.CheckOverrides(bytes.NewReader(depData), classifier, overrides, *searchDepthFlag)
+	if err != nil {
+		log.Fatalf("Failed to check overrides: %v", err)
+	}
+
+	for _, issue := range issues {
+		fmt.Printf("%s: %s: %s\n", issue.Override, issue.Kind, issue.Message)
+	}
+
+	// only generate the pruned overrides if the output path is provided
+	if *prunedOutFlag != "" {
+		if err := dependency.WriteOverrides(*prunedOutFlag, detector.PruneOverrides(overrides, issues)); err != nil {
+			log.Fatalf("Failed to write pruned overrides: %v", err)
+		}
+	}
+
+	if len(issues) > 0 {
+		log.Fatalf("%d problems found with %d overrides", len(issues), len(overrides))
+	}
+}
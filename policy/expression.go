@@ -0,0 +1,635 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expression is a compiled policy condition such as `indirect && licenceCategory == "weak-copyleft" && !localReplacement`.
+//
+// The syntax is a small subset of CEL:
+//   - literals: strings in single or double quotes, numbers, true, false and lists such as ["MIT", "ISC"]
+//   - logical operators: && (or AND), || (or OR) and ! (or NOT)
+//   - comparisons: ==, !=, <, <=, >, >= and in for list membership
+//   - functions: startsWith, endsWith, contains and matches, which can be called as name.startsWith("go.elastic.co/")
+//     or startsWith(name, "go.elastic.co/")
+type Expression struct {
+	src  string
+	root node
+}
+
+// Compile parses the expression and checks that it only refers to the given variables and known functions.
+func Compile(src string, variables []string) (*Expression, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+
+	p := &parser{tokens: tokens, variables: variables}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("invalid expression %q: unexpected %q", src, tok.text)
+	}
+
+	return &Expression{src: src, root: root}, nil
+}
+
+// Eval evaluates the expression with the given variable values, which must be strings, numbers or booleans.
+func (e *Expression) Eval(vars map[string]any) (bool, error) {
+	v, err := e.root.eval(vars)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate %q: %w", e.src, err)
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("failed to evaluate %q: result is %s instead of a boolean", e.src, typeName(v))
+	}
+
+	return b, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.src
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// punctuation is ordered so that longer operators are matched before their prefixes.
+var punctuation = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '"' || c == '\'':
+			s, n, err := scanString(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s})
+			i += n
+		case unicode.IsDigit(c):
+			n := scanWhile(src[i:], func(r rune) bool { return unicode.IsDigit(r) || r == '.' })
+			tokens = append(tokens, token{kind: tokenNumber, text: src[i : i+n]})
+			i += n
+		case unicode.IsLetter(c) || c == '_':
+			n := scanWhile(src[i:], func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' })
+			tokens = append(tokens, token{kind: tokenIdent, text: src[i : i+n]})
+			i += n
+		default:
+			matched := false
+			for _, p := range punctuation {
+				if strings.HasPrefix(src[i:], p) {
+					tokens = append(tokens, token{kind: tokenPunct, text: p})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// scanWhile returns the length in bytes of the prefix of s whose runes satisfy f.
+func scanWhile(s string, f func(rune) bool) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !f(r) {
+			break
+		}
+		n += size
+	}
+
+	return n
+}
+
+func scanString(s string) (string, int, error) {
+	quote := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case quote:
+			return sb.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			i++
+			sb.WriteByte(s[i])
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+type parser struct {
+	tokens    []token
+	pos       int
+	variables []string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators or keywords.
+func (p *parser) accept(texts ...string) bool {
+	tok := p.peek()
+	if (tok.kind == tokenPunct || tok.kind == tokenIdent) && slices.Contains(texts, tok.text) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected %q but found %q", text, p.peek().text)
+	}
+
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("||", "OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("&&", "AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!", "NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if p.accept("==", "!=", "<", "<=", ">", ">=", "in") {
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return &comparisonNode{op: tok.text, left: left, right: right}, nil
+	}
+
+	return left, nil
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	// receiver-style function calls such as name.startsWith("x")
+	for p.accept(".") {
+		tok := p.next()
+		if tok.kind != tokenIdent {
+			return nil, fmt.Errorf("expected function name after \".\" but found %q", tok.text)
+		}
+
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+
+		if n, err = mkCall(tok.text, append([]node{n}, args...)); err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return &literalNode{value: f}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+
+		if next := p.peek(); next.kind == tokenPunct && next.text == "(" {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return mkCall(tok.text, args)
+		}
+
+		if !slices.Contains(p.variables, tok.text) {
+			return nil, fmt.Errorf("unknown variable %q", tok.text)
+		}
+		return &variableNode{name: tok.text}, nil
+	case tokenPunct:
+		switch tok.text {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			var elems []node
+			for !p.accept("]") {
+				if len(elems) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+
+				elem, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				elems = append(elems, elem)
+			}
+			return &listNode{elems: elems}, nil
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
+
+func (p *parser) parseArgs() ([]node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var args []node
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+
+	return args, nil
+}
+
+// functions maps the names of the supported functions to their implementation. All of them take two strings.
+var functions = map[string]func(s, arg string) (bool, error){
+	"startsWith": func(s, prefix string) (bool, error) { return strings.HasPrefix(s, prefix), nil },
+	"endsWith":   func(s, suffix string) (bool, error) { return strings.HasSuffix(s, suffix), nil },
+	"contains":   func(s, substr string) (bool, error) { return strings.Contains(s, substr), nil },
+	"matches": func(s, pattern string) (bool, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		return re.MatchString(s), nil
+	},
+}
+
+func mkCall(name string, args []node) (node, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+
+	if len(args) != 2 {
+		return nil, fmt.Errorf("function %q expects 2 arguments but got %d", name, len(args))
+	}
+
+	// regular expressions given as literals are compiled once, when compiling the expression
+	if lit, ok := args[1].(*literalNode); ok && name == "matches" {
+		if pattern, ok := lit.value.(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+			}
+			fn = func(s, _ string) (bool, error) { return re.MatchString(s), nil }
+		}
+	}
+
+	return &callNode{name: name, fn: fn, args: args}, nil
+}
+
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(map[string]any) (any, error) {
+	return n.value, nil
+}
+
+type variableNode struct {
+	name string
+}
+
+func (n *variableNode) eval(vars map[string]any) (any, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("variable %q is not set", n.name)
+	}
+
+	switch x := v.(type) {
+	case int:
+		return float64(x), nil
+	case float32:
+		return float64(x), nil
+	}
+
+	return v, nil
+}
+
+type listNode struct {
+	elems []node
+}
+
+func (n *listNode) eval(vars map[string]any) (any, error) {
+	values := make([]any, len(n.elems))
+	for i, elem := range n.elems {
+		v, err := elem.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) eval(vars map[string]any) (any, error) {
+	left, err := evalBool(n.left, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// short-circuit evaluation
+	if left == n.or {
+		return left, nil
+	}
+
+	return evalBool(n.right, vars)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(vars map[string]any) (any, error) {
+	v, err := evalBool(n.operand, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return !v, nil
+}
+
+type comparisonNode struct {
+	op          string
+	left, right node
+}
+
+func (n *comparisonNode) eval(vars map[string]any) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := left.([]any); ok {
+		return nil, fmt.Errorf("cannot compare lists")
+	}
+
+	if n.op == "in" {
+		list, ok := right.([]any)
+		if !ok {
+			return nil, fmt.Errorf("right operand of in is %s instead of a list", typeName(right))
+		}
+		return slices.Contains(list, left), nil
+	}
+
+	if _, ok := right.([]any); ok {
+		return nil, fmt.Errorf("cannot compare %s with a list", typeName(left))
+	}
+
+	switch n.op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %s", typeName(right))
+		}
+		cmp = strings.Compare(l, r)
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %s", typeName(right))
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	default:
+		return nil, fmt.Errorf("cannot order %s values", typeName(left))
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+type callNode struct {
+	name string
+	fn   func(s, arg string) (bool, error)
+	args []node
+}
+
+func (n *callNode) eval(vars map[string]any) (any, error) {
+	strArgs := make([]string, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument %d of %s is %s instead of a string", i+1, n.name, typeName(v))
+		}
+		strArgs[i] = s
+	}
+
+	return n.fn(strArgs[0], strArgs[1])
+}
+
+func evalBool(n node, vars map[string]any) (bool, error) {
+	v, err := n.eval(vars)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("operand is %s instead of a boolean", typeName(v))
+	}
+
+	return b, nil
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []any:
+		return "a list"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
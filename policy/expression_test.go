@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpressionEval(t *testing.T) {
+	vars := map[string]any{
+		"name":             "github.com/elastic/go-sysinfo",
+		"licenceType":      "MPL-2.0",
+		"licenceCategory":  "weak-copyleft",
+		"confidence":       0.92,
+		"indirect":         true,
+		"localReplacement": false,
+	}
+	variables := []string{"name", "licenceType", "licenceCategory", "confidence", "indirect", "localReplacement"}
+
+	testCases := []struct {
+		expr string
+		want bool
+	}{
+		{expr: `indirect && licenceCategory == "weak-copyleft" && !localReplacement`, want: true},
+		{expr: `indirect AND licenceCategory == 'weak-copyleft' AND NOT localReplacement`, want: true},
+		{expr: `localReplacement || licenceType != "MPL-2.0"`, want: false},
+		{expr: `!(localReplacement || indirect)`, want: false},
+		{expr: `licenceType in ["MIT", "MPL-2.0"]`, want: true},
+		{expr: `licenceType in []`, want: false},
+		{expr: `confidence < 0.95 && confidence >= 0.9`, want: true},
+		{expr: `name.startsWith("github.com/elastic/")`, want: true},
+		{expr: `endsWith(name, "sysinfo") && name.contains("go-")`, want: true},
+		{expr: `name.matches("^github\\.com/[^/]+/go-")`, want: true},
+		{expr: `licenceType.matches(licenceType)`, want: true},
+		{expr: `licenceType < "MIT"`, want: false},
+		{expr: `true`, want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := Compile(tc.expr, variables)
+			require.NoError(t, err)
+
+			got, err := expr.Eval(vars)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExpressionUnicode(t *testing.T) {
+	expr, err := Compile(`名前 == "café" && !naïve`, []string{"名前", "naïve"})
+	require.NoError(t, err)
+
+	got, err := expr.Eval(map[string]any{"名前": "café", "naïve": false})
+	require.NoError(t, err)
+	require.True(t, got)
+
+	_, err = Compile(`name → "x"`, []string{"name"})
+	require.ErrorContains(t, err, `unexpected character '→'`)
+}
+
+func TestCompileInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"UnknownVariable":   `licence == "MIT"`,
+		"UnknownFunction":   `name.hasPrefix("go.elastic.co")`,
+		"WrongArity":        `startsWith(name)`,
+		"InvalidRegexp":     `matches(name, "[")`,
+		"UnterminatedStr":   `name == "go.elastic.co`,
+		"MissingOperand":    `indirect &&`,
+		"MissingParen":      `(indirect || localReplacement`,
+		"TrailingTokens":    `indirect localReplacement`,
+		"UnexpectedChar":    `indirect & localReplacement`,
+		"MissingListComma":  `name in ["a" "b"]`,
+		"EmptyExpression":   ``,
+		"MethodWithoutCall": `name.startsWith`,
+	}
+
+	variables := []string{"name", "indirect", "localReplacement"}
+	for name, expr := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compile(expr, variables)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestExpressionEvalTypeErrors(t *testing.T) {
+	vars := map[string]any{"name": "example.com/a", "confidence": 1.0, "indirect": true}
+	variables := []string{"name", "confidence", "indirect"}
+
+	for _, expr := range []string{
+		`name`,
+		`name && indirect`,
+		`confidence < "1"`,
+		`indirect < true`,
+		`name in "example.com/a"`,
+		`["a"] == ["a"]`,
+		`startsWith(confidence, "1")`,
+	} {
+		t.Run(expr, func(t *testing.T) {
+			e, err := Compile(expr, variables)
+			require.NoError(t, err)
+
+			_, err = e.Eval(vars)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package policy evaluates conditions over the detected information of dependencies.
+package policy // import "go.elastic.co/go-licence-detector/policy"
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"go.elastic.co/go-licence-detector/dependency"
+	"golang.org/x/mod/module"
+)
+
+// Severities of policy results.
+const (
+	SeverityError = "error"
+	SeverityWarn  = "warn"
+	SeverityInfo  = "info"
+)
+
+// Variables lists the variables available to policy conditions.
+var Variables = []string{
+	"name",
+	"version",
+	"versionTime",
+	"url",
+	"licenceType",
+	"licenceCategory",
+	"licenceFile",
+	"licenceSource",
+	"confidence",
+	"context",
+	"direct",
+	"indirect",
+	"test",
+	"tool",
+	"pseudoVersion",
+	"replaced",
+	"localReplacement",
+	"exception",
+	"approved",
+}
+
+// Policy reports a message with the given severity for each dependency matching the condition.
+type Policy struct {
+	Name      string
+	Condition *Expression
+	Severity  string
+	Message   *template.Template // executed with the dependency.Info of the matching dependency
+}
+
+// New creates a policy from the given condition and message template.
+func New(name, condition, severity, message string) (*Policy, error) {
+	if name == "" {
+		return nil, fmt.Errorf("policy must have a name")
+	}
+
+	if severity != SeverityError && severity != SeverityWarn && severity != SeverityInfo {
+		return nil, fmt.Errorf("policy %s has invalid severity %q. Valid severities are: %s, %s, %s", name, severity, SeverityError, SeverityWarn, SeverityInfo)
+	}
+
+	expr, err := Compile(condition, Variables)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s has an invalid condition: %w", name, err)
+	}
+
+	if message == "" {
+		message = name
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(message)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s has an invalid message template: %w", name, err)
+	}
+
+	return &Policy{Name: name, Condition: expr, Severity: severity, Message: tmpl}, nil
+}
+
+// Evaluate returns the result of the policy if the dependency matches the condition, or nil otherwise.
+func (p *Policy) Evaluate(depInfo *dependency.Info) (*dependency.PolicyResult, error) {
+	matches, err := p.Condition.Eval(Vars(depInfo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate policy %s for %s: %w", p.Name, depInfo.Name, err)
+	}
+
+	if !matches {
+		return nil, nil
+	}
+
+	var msg strings.Builder
+	if err := p.Message.Execute(&msg, depInfo); err != nil {
+		return nil, fmt.Errorf("failed to render message of policy %s for %s: %w", p.Name, depInfo.Name, err)
+	}
+
+	return &dependency.PolicyResult{Policy: p.Name, Severity: p.Severity, Message: msg.String()}, nil
+}
+
+// Vars returns the values of the policy variables for the given dependency.
+func Vars(depInfo *dependency.Info) map[string]any {
+	return map[string]any{
+		"name":             depInfo.Name,
+		"version":          depInfo.Version,
+		"versionTime":      depInfo.VersionTime,
+		"url":              depInfo.URL,
+		"licenceType":      depInfo.LicenceType,
+		"licenceCategory":  depInfo.LicenceCategory,
+		"licenceFile":      depInfo.LicenceFile,
+		"licenceSource":    depInfo.LicenceSource,
+		"confidence":       depInfo.LicenceConfidence,
+		"context":          depInfo.Context,
+		"direct":           depInfo.Context == dependency.ContextDirect,
+		"indirect":         depInfo.Context == dependency.ContextIndirect,
+		"test":             depInfo.Context == dependency.ContextTest,
+		"tool":             depInfo.Context == dependency.ContextTool,
+		"pseudoVersion":    module.IsPseudoVersion(depInfo.Version),
+		"replaced":         depInfo.Replaced,
+		"localReplacement": depInfo.LocalReplacement,
+		"exception":        depInfo.Exception != nil,
+		"approved":         depInfo.Approval != nil,
+	}
+}
@@ -0,0 +1,100 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/go-licence-detector/dependency"
+)
+
+func TestPolicyEvaluate(t *testing.T) {
+	p, err := New("pseudo-version-override", `pseudoVersion && licenceSource == "override"`, SeverityWarn, "{{.Name}}@{{.Version}} uses an overridden licence ({{.LicenceType}}) at a pseudo-version")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		depInfo dependency.Info
+		want    *dependency.PolicyResult
+	}{
+		{
+			name: "Matches",
+			depInfo: dependency.Info{
+				Name:          "github.com/gorhill/cronexpr",
+				Version:       "v0.0.0-20161205141322-d520615e531a",
+				LicenceType:   "GPL-3.0",
+				LicenceSource: dependency.LicenceSourceOverride,
+			},
+			want: &dependency.PolicyResult{
+				Policy:   "pseudo-version-override",
+				Severity: SeverityWarn,
+				Message:  "github.com/gorhill/cronexpr@v0.0.0-20161205141322-d520615e531a uses an overridden licence (GPL-3.0) at a pseudo-version",
+			},
+		},
+		{
+			name: "ReleaseVersion",
+			depInfo: dependency.Info{
+				Name:          "github.com/gorhill/cronexpr",
+				Version:       "v1.0.0",
+				LicenceType:   "GPL-3.0",
+				LicenceSource: dependency.LicenceSourceOverride,
+			},
+		},
+		{
+			name: "DetectedLicence",
+			depInfo: dependency.Info{
+				Name:          "github.com/gorhill/cronexpr",
+				Version:       "v0.0.0-20161205141322-d520615e531a",
+				LicenceType:   "GPL-3.0",
+				LicenceSource: dependency.LicenceSourceFile,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := p.Evaluate(&tc.depInfo)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	testCases := []struct {
+		name      string
+		policy    string
+		condition string
+		severity  string
+		message   string
+	}{
+		{name: "MissingName", condition: "indirect", severity: SeverityInfo},
+		{name: "InvalidSeverity", policy: "p", condition: "indirect", severity: "fatal"},
+		{name: "InvalidCondition", policy: "p", condition: "indirect &&", severity: SeverityInfo},
+		{name: "UnknownVariable", policy: "p", condition: "category == 'permissive'", severity: SeverityInfo},
+		{name: "InvalidMessage", policy: "p", condition: "indirect", severity: SeverityInfo, message: "{{.Name"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.policy, tc.condition, tc.severity, tc.message)
+			require.Error(t, err)
+		})
+	}
+}
@@ -19,6 +19,7 @@ package render // import "go.elastic.co/go-licence-detector/render"
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"go/build"
 	"io"
@@ -32,6 +33,7 @@ import (
 	"time"
 
 	"go.elastic.co/go-licence-detector/dependency"
+	"go.elastic.co/go-licence-detector/licence"
 	"golang.org/x/mod/semver"
 )
 
@@ -77,6 +79,39 @@ func Template(dependencies *dependency.List, templateValues KeyValueFlags, templ
 	return nil
 }
 
+// JSON writes the dependencies, including any licence exceptions that were used, as a JSON report.
+func JSON(dependencies *dependency.List, outputPath string) error {
+	return writeJSON(dependencies, outputPath)
+}
+
+// ProfileResult is the outcome of detecting dependencies with the rules of a profile.
+type ProfileResult struct {
+	Profile      string           `json:"profile"`
+	Error        string           `json:"error,omitempty"`
+	Dependencies *dependency.List `json:"dependencies,omitempty"`
+}
+
+// JSONProfiles writes the results of several profiles as a JSON report.
+func JSONProfiles(results []ProfileResult, outputPath string) error {
+	return writeJSON(results, outputPath)
+}
+
+func writeJSON(v any, outputPath string) error {
+	w, cleanup, err := mkWriter(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file %s: %w", outputPath, err)
+	}
+	defer cleanup()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
+}
+
 func mkWriter(path string) (io.Writer, func(), error) {
 	if path == "-" {
 		return os.Stdout, func() {}, nil
@@ -137,9 +172,29 @@ func Line(ch string) string {
 	return strings.Repeat(ch, 80)
 }
 
+// LicenceText returns the licence text provided by the override or the text of the licence file of the dependency.
+// If there is neither, it falls back to the canonical text of the licence type, which is labelled as such as it lacks
+// the copyright notice of the module.
 func LicenceText(depInfo dependency.Info) string {
+	if depInfo.LicenceText != "" {
+		var buf bytes.Buffer
+		additonalLicenceText(&buf, depInfo)
+		buf.WriteString("Contents of provided licence text:\n\n")
+		buf.WriteString(depInfo.LicenceText)
+		return buf.String()
+	}
+
 	if depInfo.LicenceFile == "" {
-		return "No licence file provided."
+		text, ok := licence.Text(depInfo.LicenceType)
+		if !ok {
+			return "No licence file provided."
+		}
+
+		var buf bytes.Buffer
+		additonalLicenceText(&buf, depInfo)
+		fmt.Fprintf(&buf, "No licence file provided. Canonical text of the %s licence, which does not include the copyright notice of the module:\n\n", depInfo.LicenceType)
+		buf.WriteString(text)
+		return buf.String()
 	}
 
 	var buf bytes.Buffer
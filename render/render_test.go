@@ -17,7 +17,15 @@
 
 package render
 
-import "testing"
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/go-licence-detector/dependency"
+)
 
 func TestCanonical(t *testing.T) {
 	cases := map[string]string{
@@ -72,3 +80,108 @@ func TestRevision(t *testing.T) {
 		})
 	}
 }
+
+func TestLicenceText(t *testing.T) {
+	dir := t.TempDir()
+	licenceFile := filepath.Join(dir, "LICENSE")
+	require.NoError(t, os.WriteFile(licenceFile, []byte("Copyright (c) 2020 Example\n"), 0o600))
+
+	testCases := []struct {
+		name    string
+		depInfo dependency.Info
+		want    []string
+	}{
+		{
+			name:    "LicenceFile",
+			depInfo: dependency.Info{LicenceType: "MIT", LicenceFile: licenceFile, LocalReplacement: true},
+			want:    []string{"Contents of probable licence file LICENSE:\n\nCopyright (c) 2020 Example\n"},
+		},
+		{
+			name:    "LicenceTextOverrideFile",
+			depInfo: dependency.Info{LicenceType: "MIT", LicenceFile: licenceFile, LicenceTextOverrideFile: "LICENSE"},
+			want:    []string{"Contents of provided licence file:\n\nCopyright (c) 2020 Example\n"},
+		},
+		{
+			name:    "LicenceText",
+			depInfo: dependency.Info{LicenceType: "MIT", LicenceText: "Copyright (c) 2021 Example\n"},
+			want:    []string{"Contents of provided licence text:\n\nCopyright (c) 2021 Example\n"},
+		},
+		{
+			name:    "CanonicalText",
+			depInfo: dependency.Info{LicenceType: "MIT"},
+			want:    []string{"No licence file provided. Canonical text of the MIT licence, which does not include the copyright notice of the module:\n\n", "Permission is hereby granted"},
+		},
+		{
+			name:    "NoCanonicalText",
+			depInfo: dependency.Info{LicenceType: "LicenseRef-Public-Domain"},
+			want:    []string{"No licence file provided."},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := LicenceText(tc.depInfo)
+			for _, want := range tc.want {
+				require.Contains(t, got, want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	exception := dependency.Exception{
+		Module:        "github.com/gorhill/cronexpr",
+		Licence:       "GPL-3.0",
+		Justification: "Only used in tests.",
+		Approver:      "legal@example.com",
+		Expires:       "2030-06-30",
+	}
+	deps := &dependency.List{
+		Direct: []dependency.Info{
+			{Name: "github.com/gorhill/cronexpr", LicenceType: "GPL-3.0", Exception: &exception},
+		},
+		Exceptions: []dependency.Exception{exception},
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, JSON(deps, path))
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var have dependency.List
+	require.NoError(t, json.Unmarshal(contents, &have))
+	require.Equal(t, deps.Exceptions, have.Exceptions)
+	require.Len(t, have.Direct, 1)
+	require.Equal(t, &exception, have.Direct[0].Exception)
+}
+
+func TestJSONProfiles(t *testing.T) {
+	results := []ProfileResult{
+		{
+			Profile: "distribution",
+			Error:   "dependency github.com/example/server uses licence AGPL-3.0 which is not allowed by the rules",
+		},
+		{
+			Profile: "saas",
+			Dependencies: &dependency.List{
+				Direct: []dependency.Info{{Name: "github.com/example/server", LicenceType: "AGPL-3.0"}},
+			},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, JSONProfiles(results, path))
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var have []ProfileResult
+	require.NoError(t, json.Unmarshal(contents, &have))
+	require.Len(t, have, 2)
+	require.Equal(t, results[0], have[0])
+	require.Equal(t, "saas", have[1].Profile)
+	require.Empty(t, have[1].Error)
+	require.Len(t, have[1].Dependencies.Direct, 1)
+	require.Equal(t, "AGPL-3.0", have[1].Dependencies.Direct[0].LicenceType)
+}